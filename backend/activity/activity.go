@@ -0,0 +1,154 @@
+// Package activity keeps the append-only log of who changed what on a board.
+// Entries are written with the transaction making the change, so the log never
+// disagrees with the data.
+package activity
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+	"trello-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Actions recorded in the log
+const (
+	ActionCreated       = "created"
+	ActionUpdated       = "updated"
+	ActionMoved         = "moved"
+	ActionArchived      = "archived"
+	ActionUnarchived    = "unarchived"
+	ActionDeleted       = "deleted"
+	ActionRestored      = "restored"
+	ActionMemberAdded   = "member_added"
+	ActionMemberRemoved = "member_removed"
+	// Changes to what is attached to a card, logged on the card
+	ActionCommentAdded      = "comment_added"
+	ActionCommentUpdated    = "comment_updated"
+	ActionCommentDeleted    = "comment_deleted"
+	ActionAssigneeAdded     = "assignee_added"
+	ActionAssigneeRemoved   = "assignee_removed"
+	ActionLabelAdded        = "label_added"
+	ActionLabelRemoved      = "label_removed"
+	ActionDependencyAdded   = "dependency_added"
+	ActionDependencyRemoved = "dependency_removed"
+)
+
+// Snapshot holds the fields of an entity worth keeping in the log
+type Snapshot map[string]interface{}
+
+// Entry describes one change. Before is nil for creations and After is nil for
+// deletions; for other changes both are reduced to the fields that differ.
+type Entry struct {
+	BoardID    string
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     Snapshot
+	After      Snapshot
+}
+
+// Record appends an entry to the log. Updates that changed nothing are skipped.
+func Record(tx *gorm.DB, entry Entry) error {
+	before, after := diff(entry.Before, entry.After)
+	if entry.Before != nil && entry.After != nil && len(after) == 0 {
+		return nil
+	}
+
+	return tx.Omit("Actor").Create(&models.Activity{
+		ID:         uuid.NewString(),
+		BoardID:    entry.BoardID,
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Before:     before,
+		After:      after,
+		CreatedAt:  time.Now().UTC(),
+	}).Error
+}
+
+// diff drops the fields that are the same in before and after. Values are
+// compared by their JSON encoding, which is also how they are stored.
+func diff(before, after Snapshot) (Snapshot, Snapshot) {
+	if before == nil || after == nil {
+		return before, after
+	}
+
+	changedBefore, changedAfter := Snapshot{}, Snapshot{}
+	for key, value := range after {
+		was, _ := json.Marshal(before[key])
+		is, _ := json.Marshal(value)
+		if !bytes.Equal(was, is) {
+			changedBefore[key] = before[key]
+			changedAfter[key] = value
+		}
+	}
+	return changedBefore, changedAfter
+}
+
+// Board returns the logged fields of a board
+func Board(board models.Board) Snapshot {
+	return Snapshot{
+		"name":              board.Name,
+		"archived":          board.ArchivedAt != nil,
+		"isTemplate":        board.IsTemplate,
+		"blockedMovePolicy": board.BlockedMovePolicy,
+		"wipMode":           board.WIPMode,
+	}
+}
+
+// List returns the logged fields of a list
+func List(list models.List) Snapshot {
+	return Snapshot{
+		"name":     list.Name,
+		"boardId":  list.BoardID,
+		"rank":     list.Rank,
+		"archived": list.ArchivedAt != nil,
+		"isDone":   list.IsDone,
+		"wipLimit": list.WIPLimit,
+	}
+}
+
+// Card returns the logged fields of a card
+func Card(card models.Card) Snapshot {
+	return Snapshot{
+		"title":       card.Title,
+		"description": card.Description,
+		"listId":      card.ListID,
+		"rank":        card.Rank,
+		"startDate":   card.StartDate,
+		"dueDate":     card.DueDate,
+		"dueComplete": card.DueComplete,
+		"archived":    card.ArchivedAt != nil,
+	}
+}
+
+// Member returns the logged fields of a board membership
+func Member(user models.User) Snapshot {
+	return Snapshot{"userId": user.ID, "username": user.Username}
+}
+
+// Comment returns the logged fields of a comment
+func Comment(comment models.Comment) Snapshot {
+	return Snapshot{"commentId": comment.ID, "body": comment.Body}
+}
+
+// Label returns the logged fields of a label
+func Label(label models.Label) Snapshot {
+	return Snapshot{"labelId": label.ID, "name": label.Name, "color": label.Color}
+}
+
+// FieldValue returns a card's value for a custom field, nil when it has none.
+// The field is kept whole so a change still says which field it was.
+func FieldValue(field models.CustomField, value interface{}) Snapshot {
+	return Snapshot{"customField": map[string]interface{}{"id": field.ID, "name": field.Name, "value": value}}
+}
+
+// Dependency returns the logged fields of a link between two cards
+func Dependency(blockingCardID, blockedCardID string) Snapshot {
+	return Snapshot{"blockingCardId": blockingCardID, "blockedCardId": blockedCardID}
+}
@@ -0,0 +1,116 @@
+// Command cleanup-orphans removes rows left behind by the old, non-cascading
+// deletes and then validates the cascading foreign keys.
+//
+//	go run ./cmd/cleanup-orphans -dry-run
+//	go run ./cmd/cleanup-orphans
+package main
+
+import (
+	"flag"
+	"log"
+	"trello-backend/config"
+	"trello-backend/migrations"
+
+	"github.com/joho/godotenv"
+	"gorm.io/gorm"
+)
+
+const (
+	liveBoards = `SELECT id FROM boards`
+	liveLists  = `SELECT id FROM lists WHERE board_id IN (` + liveBoards + `)`
+	liveCards  = `SELECT id FROM cards WHERE list_id IN (` + liveLists + `)`
+)
+
+// orphan describes rows whose parent no longer exists
+type orphan struct {
+	Name  string
+	Table string
+	Where string
+}
+
+// orphans are removed in order, so each condition only relies on parents that survive
+var orphans = []orphan{
+	{"lists without a board", "lists", `board_id IS NULL OR board_id NOT IN (` + liveBoards + `)`},
+	{"cards without a list", "cards", `list_id IS NULL OR list_id NOT IN (` + liveLists + `)`},
+	{"board members without a board or user", "board_members",
+		`board_id NOT IN (` + liveBoards + `) OR user_id NOT IN (SELECT id FROM users)`},
+	{"card assignees without a card or user", "card_assignees",
+		`card_id NOT IN (` + liveCards + `) OR user_id NOT IN (SELECT id FROM users)`},
+	{"card reminders without a card", "card_reminders", `card_id NOT IN (` + liveCards + `)`},
+	{"labels without a board", "labels", `board_id NOT IN (` + liveBoards + `)`},
+	{"card labels without a card or label", "card_labels",
+		`card_id NOT IN (` + liveCards + `) OR label_id NOT IN (SELECT id FROM labels)`},
+	{"custom fields without a board", "custom_fields", `board_id NOT IN (` + liveBoards + `)`},
+	{"custom field values without a card or field", "custom_field_values",
+		`card_id NOT IN (` + liveCards + `) OR field_id NOT IN (SELECT id FROM custom_fields)`},
+	{"card dependencies without a card", "card_dependencies",
+		`blocking_card_id NOT IN (` + liveCards + `) OR blocked_card_id NOT IN (` + liveCards + `)`},
+	{"comments without a card", "comments", `card_id NOT IN (` + liveCards + `)`},
+	{"mentions without a card or comment", "mentions",
+		`card_id NOT IN (` + liveCards + `) OR (comment_id IS NOT NULL AND comment_id NOT IN (SELECT id FROM comments))`},
+	{"attachments without a card", "attachments", `card_id NOT IN (` + liveCards + `)`},
+	{"subscriptions without a board, list or card", "subscriptions",
+		`(entity_type = 'board' AND entity_id NOT IN (` + liveBoards + `))` +
+			` OR (entity_type = 'list' AND entity_id NOT IN (` + liveLists + `))` +
+			` OR (entity_type = 'card' AND entity_id NOT IN (` + liveCards + `))`},
+}
+
+// strandedCards are live cards whose list is in the trash. They are moved to
+// the trash with their list so restoring the list brings them back.
+const strandedCards = `cards.deleted_at IS NULL AND cards.list_id = lists.id AND lists.deleted_at IS NOT NULL`
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report orphaned rows without removing them")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found")
+	}
+	config.ConnectDB()
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, o := range orphans {
+			if !tx.Migrator().HasTable(o.Table) {
+				continue
+			}
+
+			var count int64
+			if err := tx.Table(o.Table).Where(o.Where).Count(&count).Error; err != nil {
+				return err
+			}
+			log.Printf("%s: %d", o.Name, count)
+
+			if !*dryRun && count > 0 {
+				if err := tx.Exec("DELETE FROM " + o.Table + " WHERE " + o.Where).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		var count int64
+		if err := tx.Raw("SELECT COUNT(*) FROM cards, lists WHERE " + strandedCards).Scan(&count).Error; err != nil {
+			return err
+		}
+		log.Printf("cards left live in deleted lists: %d", count)
+
+		if !*dryRun && count > 0 {
+			if err := tx.Exec("UPDATE cards SET deleted_at = lists.deleted_at FROM lists WHERE " + strandedCards).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Cleanup failed: %v", err)
+	}
+
+	if *dryRun {
+		log.Println("Dry run, nothing was removed")
+		return
+	}
+
+	if err := migrations.ValidateCascadeForeignKeys(); err != nil {
+		log.Fatalf("Failed to validate foreign keys: %v", err)
+	}
+	log.Println("Orphaned rows removed and foreign keys validated")
+}
@@ -0,0 +1,96 @@
+package collab
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4096
+	sendBuffer     = 32
+)
+
+// Client is one socket connected to a board's channel
+type Client struct {
+	hub     *Hub
+	conn    *websocket.Conn
+	id      string // identifies the socket in the shared state
+	boardID string
+	user    User
+	send    chan []byte
+
+	// Guarded by hub.mu
+	closed bool // send has been closed
+}
+
+// readPump handles the client's messages until the socket fails or stops
+// answering pings
+func (c *Client) readPump() {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.hub.refuse(c, "Invalid message", "")
+			continue
+		}
+		c.hub.handle(c, req)
+	}
+}
+
+// writePump sends queued messages and pings until the outbox is closed or a
+// write fails. Closing the socket also ends readPump.
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case message, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// sendError tells the client a request was refused; hub.mu must be held
+func (c *Client) sendError(message string, cardID string) {
+	if c.closed {
+		return
+	}
+	data, err := json.Marshal(map[string]string{"type": TypeError, "error": message, "cardId": cardID})
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
@@ -0,0 +1,344 @@
+// Package collab runs the live collaboration channel of each board: who is
+// looking at the board or one of its cards, soft edit locks on cards and typing
+// indicators. Sockets are held by one instance, but what they share is kept in
+// the database so that users connected to different instances see each other.
+// It all belongs to the socket that created it: it is removed when the socket
+// disconnects, and expires if the socket's instance goes away.
+package collab
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// LockTTL is how long a soft lock lasts unless the client renews it
+	LockTTL = 30 * time.Second
+	// TypingTTL is how long a typing indicator lasts after the last keystroke
+	TypingTTL = 5 * time.Second
+
+	// presenceTTL is how long a socket stays listed unless its instance renews it
+	presenceTTL = 30 * time.Second
+	// sweepInterval is how often each instance reloads the state of the boards
+	// it has sockets on, which is how changes made through other instances reach it
+	sweepInterval = time.Second
+	// memberCheckInterval is how often sockets are checked against the board's
+	// members, so users removed from a board are disconnected
+	memberCheckInterval = 15 * time.Second
+)
+
+// Message types
+const (
+	TypeView   = "view"   // client: now looking at cardId, or at the board when empty
+	TypeLock   = "lock"   // client: take or renew a soft lock on a card field
+	TypeUnlock = "unlock" // client: release a soft lock
+	TypeTyping = "typing" // client: typing in a card field
+	TypeState  = "state"  // server: who is here, what is locked, who is typing
+	TypeError  = "error"  // server: a request was refused
+)
+
+// defaultField is what a lock or typing indicator covers when none is named
+const defaultField = "description"
+
+// User identifies the person behind a socket
+type User struct {
+	ID       string `json:"userId"`
+	Username string `json:"username"`
+}
+
+// Viewer is a user connected to the board, with the card they have open
+type Viewer struct {
+	User
+	CardID string `json:"cardId,omitempty"`
+}
+
+// Lock is a soft lock a user holds on a field of a card
+type Lock struct {
+	User
+	CardID    string    `json:"cardId"`
+	Field     string    `json:"field"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// Typing shows that a user is typing in a field of a card
+type Typing struct {
+	User
+	CardID    string    `json:"cardId"`
+	Field     string    `json:"field"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// State is sent to everyone on a board whenever it changes
+type State struct {
+	Type    string   `json:"type"`
+	Viewers []Viewer `json:"viewers"`
+	Locks   []Lock   `json:"locks"`
+	Typing  []Typing `json:"typing"`
+}
+
+// request is a message from a client
+type request struct {
+	Type   string `json:"type"`
+	CardID string `json:"cardId"`
+	Field  string `json:"field"`
+}
+
+// target is a field of a card
+type target struct {
+	cardID string
+	field  string
+}
+
+// room is the sockets this instance holds on one board
+type room struct {
+	clients map[*Client]struct{}
+	state   []byte // the last state sent to them
+}
+
+// Hub keeps the rooms of all boards with clients connected to this instance
+type Hub struct {
+	mu    sync.Mutex
+	rooms map[string]*room
+	// cardOnBoard reports whether a card belongs to a board
+	cardOnBoard func(boardID, cardID string) bool
+	// isMember reports whether a user may still access a board
+	isMember func(boardID, userID string) bool
+}
+
+// NewHub returns an empty hub and starts keeping its rooms up to date.
+// cardOnBoard is used to check the cards clients refer to, and isMember to
+// check that the users behind open sockets are still on their board.
+func NewHub(cardOnBoard func(boardID, cardID string) bool, isMember func(boardID, userID string) bool) *Hub {
+	h := &Hub{rooms: make(map[string]*room), cardOnBoard: cardOnBoard, isMember: isMember}
+	go h.sweep()
+	return h
+}
+
+// Serve runs a client on a board's channel until its socket closes, then
+// releases everything the client held
+func (h *Hub) Serve(conn *websocket.Conn, boardID string, user User) {
+	client := &Client{hub: h, conn: conn, id: uuid.NewString(), boardID: boardID, user: user, send: make(chan []byte, sendBuffer)}
+	if err := join(client, time.Now().UTC()); err != nil {
+		log.Printf("Collaboration: failed to join board %s: %v", boardID, err)
+		conn.Close()
+		return
+	}
+
+	h.mu.Lock()
+	r := h.rooms[boardID]
+	if r == nil {
+		r = &room{clients: make(map[*Client]struct{})}
+		h.rooms[boardID] = r
+	}
+	r.clients[client] = struct{}{}
+	h.mu.Unlock()
+	h.refresh(boardID, client)
+
+	go client.writePump()
+	client.readPump()
+	h.leave(client)
+}
+
+// handle applies a request from a client
+func (h *Hub) handle(c *Client, req request) {
+	if req.Field == "" {
+		req.Field = defaultField
+	}
+	if req.CardID != "" && !h.cardOnBoard(c.boardID, req.CardID) {
+		h.refuse(c, "Card not found on this board", req.CardID)
+		return
+	}
+
+	now := time.Now().UTC()
+	var err error
+	switch req.Type {
+	case TypeView:
+		err = view(c, req.CardID)
+
+	case TypeLock:
+		if req.CardID == "" {
+			h.refuse(c, "cardId is required", "")
+			return
+		}
+		var holder string
+		if holder, err = lock(c, target{req.CardID, req.Field}, now); err == nil && holder != "" {
+			h.refuse(c, holder+" is editing this card", req.CardID)
+			return
+		}
+
+	case TypeUnlock:
+		err = unlock(c, target{req.CardID, req.Field})
+
+	case TypeTyping:
+		if req.CardID == "" {
+			h.refuse(c, "cardId is required", "")
+			return
+		}
+		err = typing(c, target{req.CardID, req.Field}, now)
+
+	default:
+		h.refuse(c, "Unknown message type", "")
+		return
+	}
+	if err != nil {
+		log.Printf("Collaboration: failed to handle %s on board %s: %v", req.Type, c.boardID, err)
+		h.refuse(c, "Request failed, try again", req.CardID)
+		return
+	}
+
+	h.refresh(c.boardID, nil)
+}
+
+// refuse sends an error to a client that may be leaving
+func (h *Hub) refuse(c *Client, message string, cardID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	c.sendError(message, cardID)
+}
+
+// leave removes a disconnected client and releases its locks, typing indicator
+// and presence. Clients dropped for falling behind end up here too, once their
+// socket closes.
+func (h *Hub) leave(c *Client) {
+	h.mu.Lock()
+	if r := h.rooms[c.boardID]; r != nil {
+		h.drop(r, c)
+		if len(r.clients) == 0 {
+			delete(h.rooms, c.boardID)
+		}
+	}
+	h.mu.Unlock()
+
+	if err := release(c); err != nil {
+		log.Printf("Collaboration: failed to release client on board %s: %v", c.boardID, err)
+	}
+	h.refresh(c.boardID, nil)
+}
+
+// drop removes a client from its room and closes its outbox, which closes its
+// socket; h.mu must be held
+func (h *Hub) drop(r *room, c *Client) {
+	if _, ok := r.clients[c]; !ok {
+		return
+	}
+	delete(r.clients, c)
+	c.closed = true
+	close(c.send)
+}
+
+// sweep keeps this instance's rooms up to date: it renews their presence and
+// sends them changes, whether made here or through another instance, and the
+// expiry of locks and typing indicators
+func (h *Hub) sweep() {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	var renewed, checked time.Time
+	for range ticker.C {
+		now := time.Now().UTC()
+
+		h.mu.Lock()
+		boardIDs := make([]string, 0, len(h.rooms))
+		var clients []*Client
+		for boardID, r := range h.rooms {
+			boardIDs = append(boardIDs, boardID)
+			for c := range r.clients {
+				clients = append(clients, c)
+			}
+		}
+		h.mu.Unlock()
+		if len(boardIDs) == 0 {
+			continue
+		}
+
+		if now.Sub(renewed) >= presenceTTL/3 {
+			if err := renew(clients, now); err != nil {
+				log.Printf("Collaboration: failed to renew presence: %v", err)
+			} else {
+				renewed = now
+			}
+		}
+		if now.Sub(checked) >= memberCheckInterval {
+			h.expel(clients)
+			checked = now
+		}
+		for _, boardID := range boardIDs {
+			h.refresh(boardID, nil)
+		}
+	}
+}
+
+// expel disconnects the clients whose user is no longer a member of their
+// board. What they held is released when their socket closes.
+func (h *Hub) expel(clients []*Client) {
+	members := make(map[[2]string]bool)
+	for _, c := range clients {
+		key := [2]string{c.boardID, c.user.ID}
+		member, ok := members[key]
+		if !ok {
+			member = h.isMember(c.boardID, c.user.ID)
+			members[key] = member
+		}
+		if member {
+			continue
+		}
+
+		h.mu.Lock()
+		if r := h.rooms[c.boardID]; r != nil {
+			c.sendError("Access denied", "")
+			h.drop(r, c)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// refresh loads a board's state and sends it to the board's clients on this
+// instance if it changed since they were last sent it. newcomer, if set, is
+// sent it either way.
+func (h *Hub) refresh(boardID string, newcomer *Client) {
+	state, err := loadState(boardID, time.Now().UTC())
+	if err != nil {
+		log.Printf("Collaboration: failed to load board %s: %v", boardID, err)
+		return
+	}
+	message, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r := h.rooms[boardID]
+	if r == nil {
+		return
+	}
+	if bytes.Equal(message, r.state) {
+		if newcomer != nil {
+			h.send(r, newcomer, message)
+		}
+		return
+	}
+	r.state = message
+	for c := range r.clients {
+		h.send(r, c, message)
+	}
+}
+
+// send queues a message for a client, dropping the client if it is too slow to
+// keep up; h.mu must be held
+func (h *Hub) send(r *room, c *Client, message []byte) {
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- message:
+	default:
+		h.drop(r, c)
+	}
+}
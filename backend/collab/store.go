@@ -0,0 +1,141 @@
+package collab
+
+import (
+	"sort"
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// join lists a new socket on its board
+func join(c *Client, now time.Time) error {
+	return config.DB.Create(&models.CollabPresence{
+		ClientID:  c.id,
+		BoardID:   c.boardID,
+		UserID:    c.user.ID,
+		Username:  c.user.Username,
+		ExpiresAt: now.Add(presenceTTL),
+	}).Error
+}
+
+// renew keeps the given sockets listed, and clears out what expired
+func renew(clients []*Client, now time.Time) error {
+	ids := make([]string, len(clients))
+	for i, c := range clients {
+		ids[i] = c.id
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(ids) > 0 {
+			if err := tx.Model(&models.CollabPresence{}).Where("client_id IN ?", ids).
+				Update("expires_at", now.Add(presenceTTL)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("expires_at <= ?", now).Delete(&models.CollabPresence{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("expires_at <= ?", now).Delete(&models.CollabLock{}).Error
+	})
+}
+
+// release removes a socket along with its locks and typing indicator
+func release(c *Client) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("client_id = ?", c.id).Delete(&models.CollabLock{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("client_id = ?", c.id).Delete(&models.CollabPresence{}).Error
+	})
+}
+
+// view records the card a socket has open; empty means the board itself
+func view(c *Client, cardID string) error {
+	return config.DB.Model(&models.CollabPresence{}).Where("client_id = ?", c.id).Update("card_id", cardID).Error
+}
+
+// typing shows a socket's user typing in a field until TypingTTL from now
+func typing(c *Client, key target, now time.Time) error {
+	return config.DB.Model(&models.CollabPresence{}).Where("client_id = ?", c.id).Updates(map[string]interface{}{
+		"typing_card_id": key.cardID,
+		"typing_field":   key.field,
+		"typing_until":   now.Add(TypingTTL),
+	}).Error
+}
+
+// lock takes or renews a soft lock for the socket's user. When another user
+// holds the lock it is left alone and their name is returned.
+func lock(c *Client, key target, now time.Time) (string, error) {
+	held := models.CollabLock{
+		BoardID:   c.boardID,
+		CardID:    key.cardID,
+		Field:     key.field,
+		ClientID:  c.id,
+		UserID:    c.user.ID,
+		Username:  c.user.Username,
+		ExpiresAt: now.Add(LockTTL),
+	}
+	// Locks are per user, so another tab of the same user can take one over.
+	// Expired locks go to whoever asks.
+	result := config.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "board_id"}, {Name: "card_id"}, {Name: "field"}},
+		DoUpdates: clause.AssignmentColumns([]string{"client_id", "user_id", "username", "expires_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{clause.Expr{
+			SQL:  "collab_locks.user_id = excluded.user_id OR collab_locks.expires_at <= ?",
+			Vars: []interface{}{now},
+		}}},
+	}).Create(&held)
+	if result.Error != nil || result.RowsAffected > 0 {
+		return "", result.Error
+	}
+
+	var holder models.CollabLock
+	if err := config.DB.Where("board_id = ? AND card_id = ? AND field = ?", c.boardID, key.cardID, key.field).
+		First(&holder).Error; err != nil {
+		return "", err
+	}
+	return holder.Username, nil
+}
+
+// unlock releases a lock if the socket's user holds it, from whichever tab
+func unlock(c *Client, key target) error {
+	return config.DB.Where("board_id = ? AND card_id = ? AND field = ? AND user_id = ?",
+		c.boardID, key.cardID, key.field, c.user.ID).Delete(&models.CollabLock{}).Error
+}
+
+// loadState reads who is on a board, from any instance, and what they hold
+func loadState(boardID string, now time.Time) (State, error) {
+	state := State{Type: TypeState, Viewers: []Viewer{}, Locks: []Lock{}, Typing: []Typing{}}
+
+	var presences []models.CollabPresence
+	if err := config.DB.Where("board_id = ? AND expires_at > ?", boardID, now).
+		Order("username, client_id").Find(&presences).Error; err != nil {
+		return state, err
+	}
+	for _, presence := range presences {
+		user := User{ID: presence.UserID, Username: presence.Username}
+		state.Viewers = append(state.Viewers, Viewer{User: user, CardID: presence.CardID})
+		if presence.TypingUntil != nil && presence.TypingUntil.After(now) {
+			state.Typing = append(state.Typing, Typing{
+				User: user, CardID: presence.TypingCardID, Field: presence.TypingField, ExpiresAt: presence.TypingUntil.UTC(),
+			})
+		}
+	}
+	sort.SliceStable(state.Typing, func(i, j int) bool { return state.Typing[i].CardID < state.Typing[j].CardID })
+
+	var locks []models.CollabLock
+	if err := config.DB.Where("board_id = ? AND expires_at > ?", boardID, now).
+		Order("card_id, field").Find(&locks).Error; err != nil {
+		return state, err
+	}
+	for _, held := range locks {
+		state.Locks = append(state.Locks, Lock{
+			User:   User{ID: held.UserID, Username: held.Username},
+			CardID: held.CardID, Field: held.Field, ExpiresAt: held.ExpiresAt.UTC(),
+		})
+	}
+	return state, nil
+}
@@ -0,0 +1,16 @@
+package config
+
+import "strings"
+
+// AllowedOrigins are the frontend origins allowed to call the API and open sockets
+var AllowedOrigins = []string{"http://localhost:3000", "http://localhost:3000/"} // Allow with/without trailing slash
+
+// IsAllowedOrigin reports whether origin is one of AllowedOrigins
+func IsAllowedOrigin(origin string) bool {
+	for _, allowed := range AllowedOrigins {
+		if strings.TrimSuffix(allowed, "/") == strings.TrimSuffix(origin, "/") {
+			return true
+		}
+	}
+	return false
+}
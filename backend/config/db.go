@@ -10,12 +10,8 @@ import (
 
 var DB *gorm.DB
 
-func ConnectDB() {
-	// err := godotenv.Load()
-	// if err != nil {
-	// 	log.Fatal("Error loading .env file")
-	// }
-
+// DSN returns the connection string built from the POSTGRES_* variables
+func DSN() string {
 	host := os.Getenv("POSTGRES_HOST")
 	user := os.Getenv("POSTGRES_USER")
 	password := os.Getenv("POSTGRES_PASSWORD")
@@ -23,11 +19,18 @@ func ConnectDB() {
 	port := os.Getenv("POSTGRES_PORT")
 	sslmode := "disable"
 
-	dsn := "host=" + host + " user=" + user + " password=" + password + " dbname=" + dbname + " port=" + port + " sslmode=" + sslmode + " prefer_simple_protocol=true"
+	return "host=" + host + " user=" + user + " password=" + password + " dbname=" + dbname + " port=" + port + " sslmode=" + sslmode + " prefer_simple_protocol=true"
+}
+
+func ConnectDB() {
+	// err := godotenv.Load()
+	// if err != nil {
+	// 	log.Fatal("Error loading .env file")
+	// }
 
 	// Tambahkan konfigurasi koneksi
 	pgConfig := postgres.Config{
-		DSN:                  dsn,
+		DSN:                  DSN(),
 		PreferSimpleProtocol: true,
 	}
 
@@ -52,3 +55,13 @@ func ConnectDB() {
 
 	log.Println("Database connected")
 }
+
+// ListenDSN returns the connection string for LISTEN connections. Poolers in
+// transaction mode don't support LISTEN, so EVENTS_DATABASE_URL can point at a
+// direct connection instead; it defaults to DSN().
+func ListenDSN() string {
+	if dsn := os.Getenv("EVENTS_DATABASE_URL"); dsn != "" {
+		return dsn
+	}
+	return DSN()
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"os"
+	"strings"
+)
+
+// APIURL is the public address of this API, used in links sent by email.
+// It is read from API_URL (default http://localhost:8080).
+func APIURL() string {
+	return urlFromEnv("API_URL", "http://localhost:8080")
+}
+
+// AppURL is the public address of the frontend, used in links sent by email.
+// It is read from APP_URL (default http://localhost:3000).
+func AppURL() string {
+	return urlFromEnv("APP_URL", "http://localhost:3000")
+}
+
+func urlFromEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return strings.TrimSuffix(value, "/")
+	}
+	return fallback
+}
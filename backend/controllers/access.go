@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"net/http"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// isBoardMember reports whether the user owns the board or is one of its members.
+// The board's Members must be preloaded.
+func isBoardMember(board models.Board, userID string) bool {
+	if board.OwnerID == userID {
+		return true
+	}
+	for _, member := range board.Members {
+		if member.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// findMemberBoard loads a board and checks that the current user can access it.
+// It writes the error response and returns false when the board is missing or off-limits.
+func findMemberBoard(c *gin.Context, boardID string) (models.Board, bool) {
+	var board models.Board
+	if err := config.DB.Preload("Members").Where("id = ?", boardID).First(&board).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Board not found"})
+		return board, false
+	}
+
+	userID, _ := c.Get("userID")
+	if !isBoardMember(board, userID.(string)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return board, false
+	}
+	return board, true
+}
+
+// findOwnedBoard loads a board and checks that the current user owns it, for
+// settings only the owner may change. It writes the error response and returns false otherwise.
+func findOwnedBoard(c *gin.Context, boardID string) (models.Board, bool) {
+	var board models.Board
+	if err := config.DB.Where("id = ?", boardID).First(&board).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Board not found"})
+		return board, false
+	}
+
+	userID, _ := c.Get("userID")
+	if board.OwnerID != userID.(string) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the board owner can do this"})
+		return board, false
+	}
+	return board, true
+}
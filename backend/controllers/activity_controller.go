@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// logActivity records a change made by the current user as part of tx
+func logActivity(c *gin.Context, tx *gorm.DB, entry activity.Entry) error {
+	userID, _ := c.Get("userID")
+	entry.ActorID = userID.(string)
+	return activity.Record(tx, entry)
+}
+
+// GetBoardActivity returns the activity feed of a board, newest first
+func GetBoardActivity(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	activityFeed(c, config.DB.Where("board_id = ?", board.ID))
+}
+
+// GetCardActivity returns the activity feed of a card, newest first
+func GetCardActivity(c *gin.Context) {
+	// Cards in the trash keep their feed, so "who deleted this?" can be answered
+	var card models.Card
+	if err := config.DB.Unscoped().Preload("List", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	activityFeed(c, config.DB.Where("entity_type = ? AND entity_id = ?", models.EntityCard, card.ID))
+}
+
+// activityFeed writes one page of the activities selected by query. "limit" sets
+// the page size and "before" is the cursor returned with the previous page.
+func activityFeed(c *gin.Context, query *gorm.DB) {
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+
+	if before := c.Query("before"); before != "" {
+		var cursor models.Activity
+		if err := config.DB.Where("id = ?", before).First(&cursor).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	// One extra row tells whether there is another page
+	var activities []models.Activity
+	if err := query.Preload("Actor").Order("created_at DESC, id DESC").Limit(limit + 1).
+		Find(&activities).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get activity"})
+		return
+	}
+
+	page := models.ActivityPage{Activities: []models.ActivityResponse{}}
+	if len(activities) > limit {
+		activities = activities[:limit]
+		page.NextCursor = activities[limit-1].ID
+	}
+	for _, entry := range activities {
+		page.Activities = append(page.Activities, models.ActivityResponse{
+			ID:      entry.ID,
+			BoardID: entry.BoardID,
+			Actor: models.UserSummary{
+				ID:       entry.Actor.ID,
+				Username: entry.Actor.Username,
+			},
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			Before:     entry.Before,
+			After:      entry.After,
+			CreatedAt:  entry.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// logListChange records how a list changed from before, reading its current
+// state inside tx so rank changes made by moveList are included. A list that
+// left fromBoardID is logged on that board as well.
+func logListChange(c *gin.Context, tx *gorm.DB, action string, fromBoardID string, listID string, before activity.Snapshot) error {
+	var list models.List
+	if err := tx.Where("id = ?", listID).First(&list).Error; err != nil {
+		return err
+	}
+
+	entry := activity.Entry{
+		BoardID: list.BoardID, Action: action,
+		EntityType: models.EntityList, EntityID: list.ID, Before: before, After: activity.List(list),
+	}
+	if err := logActivity(c, tx, entry); err != nil {
+		return err
+	}
+	if fromBoardID != list.BoardID {
+		entry.BoardID = fromBoardID
+		return logActivity(c, tx, entry)
+	}
+	return nil
+}
+
+// logCardChange records how a card changed from before to after. A card that
+// changed lists is logged as moved, and a move between boards is logged on both.
+func logCardChange(c *gin.Context, tx *gorm.DB, fromBoardID, toBoardID string, before, after models.Card) error {
+	entry := activity.Entry{
+		BoardID: toBoardID, Action: activity.ActionUpdated,
+		EntityType: models.EntityCard, EntityID: after.ID, Before: activity.Card(before), After: activity.Card(after),
+	}
+	if before.ListID != after.ListID {
+		entry.Action = activity.ActionMoved
+	}
+	if fromBoardID == toBoardID {
+		return logActivity(c, tx, entry)
+	}
+
+	entry.Before["boardId"], entry.After["boardId"] = fromBoardID, toBoardID
+	if err := logActivity(c, tx, entry); err != nil {
+		return err
+	}
+	entry.BoardID = fromBoardID
+	return logActivity(c, tx, entry)
+}
+
+// pageLimit reads the page size of a feed from the limit query parameter,
+// responding with an error when it is out of range
+func pageLimit(c *gin.Context) (int, bool) {
+	value := c.Query("limit")
+	if value == "" {
+		return defaultPageLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > maxPageLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and " + strconv.Itoa(maxPageLimit)})
+		return 0, false
+	}
+	return limit, true
+}
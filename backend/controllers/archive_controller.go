@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// archiveAction is the activity action for an archive state change
+func archiveAction(archived bool) string {
+	if archived {
+		return activity.ActionArchived
+	}
+	return activity.ActionUnarchived
+}
+
+// archivedAt returns the archive timestamp to store for the requested state
+func archivedAt(archived bool) *time.Time {
+	if !archived {
+		return nil
+	}
+	now := time.Now().UTC()
+	return &now
+}
+
+func setBoardArchived(c *gin.Context, archived bool) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&board).Update("archived_at", archivedAt(archived)).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: archiveAction(archived), EntityType: models.EntityBoard, EntityID: board.ID,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update board"})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// ArchiveBoard hides a board from the board overview
+func ArchiveBoard(c *gin.Context) {
+	setBoardArchived(c, true)
+}
+
+// UnarchiveBoard brings an archived board back
+func UnarchiveBoard(c *gin.Context) {
+	setBoardArchived(c, false)
+}
+
+func setListArchived(c *gin.Context, archived bool) {
+	var list models.List
+	if err := config.DB.Where("id = ?", c.Param("listId")).First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, list.BoardID); !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&list).Update("archived_at", archivedAt(archived)).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: list.BoardID, Action: archiveAction(archived), EntityType: models.EntityList, EntityID: list.ID,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+		return
+	}
+
+	publishEvent(c, list.BoardID, events.ListUpdated, list)
+	c.JSON(http.StatusOK, list)
+}
+
+// ArchiveList hides a list and its cards from the board
+func ArchiveList(c *gin.Context) {
+	setListArchived(c, true)
+}
+
+// UnarchiveList brings an archived list back
+func UnarchiveList(c *gin.Context) {
+	setListArchived(c, false)
+}
+
+func setCardArchived(c *gin.Context, archived bool) {
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	card.ArchivedAt = archivedAt(archived)
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&card).Updates(map[string]interface{}{
+			"archived_at": card.ArchivedAt,
+			"version":     gorm.Expr("version + 1"),
+		}).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: card.List.BoardID, Action: archiveAction(archived), EntityType: models.EntityCard, EntityID: card.ID,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
+		return
+	}
+	card.Version++
+
+	publishEvent(c, card.List.BoardID, events.CardUpdated, card)
+	c.JSON(http.StatusOK, card)
+}
+
+// ArchiveCard hides a card from its list
+func ArchiveCard(c *gin.Context) {
+	setCardArchived(c, true)
+}
+
+// UnarchiveCard brings an archived card back
+func UnarchiveCard(c *gin.Context) {
+	setCardArchived(c, false)
+}
+
+// GetDeletedBoards lists the current user's boards that are in the trash
+func GetDeletedBoards(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var boards []models.Board
+	if err := config.DB.Unscoped().
+		Where("owner_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").Find(&boards).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deleted boards"})
+		return
+	}
+
+	retention := config.TrashRetention()
+	items := make([]models.TrashItem, len(boards))
+	for i, board := range boards {
+		items[i] = models.TrashItem{
+			ID:        board.ID,
+			Name:      board.Name,
+			DeletedAt: board.DeletedAt.Time,
+			PurgeAt:   board.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// GetBoardTrash lists the deleted lists and cards of a board
+func GetBoardTrash(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var lists []models.List
+	if err := config.DB.Unscoped().
+		Where("board_id = ? AND deleted_at IS NOT NULL", board.ID).
+		Order("deleted_at DESC").Find(&lists).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deleted lists"})
+		return
+	}
+
+	var cards []models.Card
+	if err := config.DB.Unscoped().
+		Where("deleted_at IS NOT NULL AND list_id IN (?)",
+			config.DB.Unscoped().Model(&models.List{}).Select("id").Where("board_id = ?", board.ID)).
+		Order("deleted_at DESC").Find(&cards).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deleted cards"})
+		return
+	}
+
+	retention := config.TrashRetention()
+	response := models.TrashResponse{
+		Lists: make([]models.TrashItem, len(lists)),
+		Cards: make([]models.TrashItem, len(cards)),
+	}
+	for i, list := range lists {
+		response.Lists[i] = models.TrashItem{
+			ID:        list.ID,
+			Name:      list.Name,
+			DeletedAt: list.DeletedAt.Time,
+			PurgeAt:   list.DeletedAt.Time.Add(retention),
+		}
+	}
+	for i, card := range cards {
+		response.Cards[i] = models.TrashItem{
+			ID:        card.ID,
+			Name:      card.Title,
+			ListID:    card.ListID,
+			DeletedAt: card.DeletedAt.Time,
+			PurgeAt:   card.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreBoard takes a board out of the trash, along with the lists and cards deleted with it
+func RestoreBoard(c *gin.Context) {
+	boardID := c.Param("boardId")
+	userID, _ := c.Get("userID")
+
+	var board models.Board
+	if err := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", boardID).First(&board).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted board not found"})
+		return
+	}
+
+	// Only the owner can restore the board
+	if board.OwnerID != userID.(string) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	deletedAt := board.DeletedAt.Time
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		lists := tx.Unscoped().Model(&models.List{}).Select("id").Where("board_id = ?", board.ID)
+		if err := tx.Unscoped().Model(&models.Card{}).
+			Where("deleted_at = ? AND list_id IN (?)", deletedAt, lists).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&models.List{}).
+			Where("board_id = ? AND deleted_at = ?", board.ID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&board).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionRestored, EntityType: models.EntityBoard, EntityID: board.ID,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore board"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Board restored successfully"})
+}
+
+// RestoreList takes a list out of the trash, along with the cards deleted with it
+func RestoreList(c *gin.Context) {
+	listID := c.Param("listId")
+
+	var list models.List
+	if err := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", listID).First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted list not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, list.BoardID); !ok {
+		return
+	}
+
+	deletedAt := list.DeletedAt.Time
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Card{}).
+			Where("list_id = ? AND deleted_at = ?", list.ID, deletedAt).
+			Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&list).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: list.BoardID, Action: activity.ActionRestored, EntityType: models.EntityList, EntityID: list.ID,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore list"})
+		return
+	}
+
+	list.DeletedAt = gorm.DeletedAt{}
+	publishEvent(c, list.BoardID, events.ListCreated, list)
+
+	c.JSON(http.StatusOK, gin.H{"message": "List restored successfully"})
+}
+
+// RestoreCard takes a card out of the trash. Its list must not be deleted.
+func RestoreCard(c *gin.Context) {
+	cardID := c.Param("cardId")
+
+	var card models.Card
+	if err := config.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", cardID).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted card not found"})
+		return
+	}
+
+	var list models.List
+	if err := config.DB.Where("id = ?", card.ListID).First(&list).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Restore the card's list first"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, list.BoardID); !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&card).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: list.BoardID, Action: activity.ActionRestored, EntityType: models.EntityCard, EntityID: card.ID,
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore card"})
+		return
+	}
+
+	card.DeletedAt = gorm.DeletedAt{}
+	publishEvent(c, list.BoardID, events.CardCreated, card)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Card restored successfully"})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"net/http"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+	"trello-backend/subscriptions"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AddCardAssignee assigns a board member to a card
+func AddCardAssignee(c *gin.Context) {
+	cardID := c.Param("cardId")
+
+	var input struct {
+		UserID string `json:"userId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", cardID).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, card.List.BoardID)
+	if !ok {
+		return
+	}
+
+	// Only board members can be assigned
+	if !isBoardMember(board, input.UserID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User is not a member of this board"})
+		return
+	}
+
+	// Assignees start watching the card they are assigned to
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		assigned, err := linkedToCard(tx, "card_assignees", "user_id", card.ID, input.UserID)
+		if err != nil || assigned {
+			return err
+		}
+		var user models.User
+		if err := tx.Where("id = ?", input.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&card).Association("Assignees").Append(&user); err != nil {
+			return err
+		}
+		if err := subscriptions.Watch(tx, user.ID, models.EntityCard, card.ID); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionAssigneeAdded,
+			EntityType: models.EntityCard, EntityID: card.ID, After: activity.Member(user),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign user"})
+		return
+	}
+
+	publishEvent(c, board.ID, events.CardAssigned, gin.H{
+		"cardId": card.ID, "listId": card.ListID, "title": card.Title, "userId": input.UserID,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Assignee added successfully"})
+}
+
+// RemoveCardAssignee removes a user from a card's assignees
+func RemoveCardAssignee(c *gin.Context) {
+	cardID := c.Param("cardId")
+	assigneeID := c.Param("userId")
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", cardID).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		assigned, err := linkedToCard(tx, "card_assignees", "user_id", card.ID, assigneeID)
+		if err != nil || !assigned {
+			return err
+		}
+		var user models.User
+		if err := tx.Where("id = ?", assigneeID).First(&user).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&card).Association("Assignees").Delete(&user); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: card.List.BoardID, Action: activity.ActionAssigneeRemoved,
+			EntityType: models.EntityCard, EntityID: card.ID, Before: activity.Member(user),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove assignee"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Assignee removed successfully"})
+}
+
+// linkedToCard reports whether a card's join table, such as its assignees or
+// labels, already links the card to id
+func linkedToCard(tx *gorm.DB, table, column, cardID, id string) (bool, error) {
+	var count int64
+	err := tx.Table(table).Where("card_id = ? AND "+column+" = ?", cardID, id).Count(&count).Error
+	return count > 0, err
+}
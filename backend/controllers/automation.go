@@ -0,0 +1,433 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/mentions"
+	"trello-backend/models"
+	"trello-backend/subscriptions"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxRuleChain is how many rules may run one after another, each on the events
+// of the one before, before the chain is cut off
+const maxRuleChain = 5
+
+var errNotBoardMember = errors.New("user is not a member of this board")
+
+// ruleEvent holds the fields of an event payload that rules look at. Card
+// events carry the card itself, label events its ID.
+type ruleEvent struct {
+	ID      string `json:"id"`
+	CardID  string `json:"cardId"`
+	LabelID string `json:"labelId"`
+}
+
+// pendingEvent is an event a rule publishes once its changes are committed
+type pendingEvent struct {
+	Type string
+	Data interface{}
+}
+
+// RunAutomations runs the board's enabled rules that an event triggers, in the
+// order they were created. It is registered with events.Handle.
+func RunAutomations(event events.Event) {
+	switch event.Type {
+	case models.TriggerCardCreated, models.TriggerCardMoved, models.TriggerDueDate, models.TriggerLabelAdded:
+	default:
+		return
+	}
+
+	var payload ruleEvent
+	if err := json.Unmarshal(event.Data, &payload); err != nil {
+		log.Printf("Failed to read %s event for automation: %v", event.Type, err)
+		return
+	}
+	cardID := payload.CardID
+	if cardID == "" {
+		cardID = payload.ID
+	}
+
+	var rules []models.AutomationRule
+	if err := config.DB.Where("board_id = ? AND enabled = ?", event.BoardID, true).
+		Order("created_at, id").Find(&rules).Error; err != nil {
+		log.Printf("Failed to find automation rules for %s event: %v", event.Type, err)
+		return
+	}
+	for _, rule := range rules {
+		if rule.Trigger.Type != event.Type {
+			continue
+		}
+		if rule.Trigger.LabelID != "" && rule.Trigger.LabelID != payload.LabelID {
+			continue
+		}
+		runRule(rule, event, cardID)
+	}
+}
+
+// runRule checks a triggered rule against the card and, if it matches, applies
+// its actions and writes the run to the execution log
+func runRule(rule models.AutomationRule, event events.Event, cardID string) {
+	board, card, err := loadRuleCard(rule.BoardID, cardID)
+	if err != nil {
+		// The card is gone, archived or on another board by now
+		return
+	}
+	if !ruleMatches(rule, card) {
+		return
+	}
+
+	run := models.AutomationRun{
+		ID:        uuid.NewString(),
+		RuleID:    rule.ID,
+		BoardID:   rule.BoardID,
+		CardID:    card.ID,
+		EventType: event.Type,
+	}
+	var pending []pendingEvent
+	switch {
+	case slices.Contains(event.Rules, rule.ID) || len(event.Rules) >= maxRuleChain:
+		run.Status = models.RunLoopPrevented
+	case !isBoardMember(board, rule.CreatorID):
+		// Rules act as their creator, who may no longer change this board; a dry
+		// run would report changes the rule can't make
+		run.Status = models.RunFailed
+		run.Error = "the rule's creator is no longer on the board"
+	case rule.DryRun:
+		run.Status = models.RunDryRun
+		run.Actions = describeActions(rule.Actions)
+	default:
+		run.Actions = describeActions(rule.Actions)
+		pending, err = applyRule(rule, board, card)
+		if err != nil {
+			run.Status = models.RunFailed
+			run.Error = err.Error()
+		} else {
+			run.Status = models.RunSucceeded
+		}
+	}
+
+	if err := config.DB.Omit("Rule").Create(&run).Error; err != nil {
+		log.Printf("Failed to log run of automation rule %s: %v", rule.ID, err)
+	}
+
+	// Events from the rule carry the chain so far, which is how loops are caught
+	chain := append(slices.Clone(event.Rules), rule.ID)
+	for _, p := range pending {
+		published, err := events.New(rule.BoardID, p.Type, rule.CreatorID, p.Data)
+		if err == nil {
+			published.Rules = chain
+			err = events.Publish(published)
+		}
+		if err != nil {
+			log.Printf("Failed to publish %s event: %v", p.Type, err)
+		}
+	}
+}
+
+// loadRuleCard loads a live card on the board, with what conditions look at
+func loadRuleCard(boardID, cardID string) (models.Board, models.Card, error) {
+	var card models.Card
+	if err := config.DB.Preload("List").Preload("Labels").Preload("Assignees").
+		Where("id = ? AND archived_at IS NULL AND is_template = ?", cardID, false).First(&card).Error; err != nil {
+		return models.Board{}, card, err
+	}
+	if card.List.BoardID != boardID {
+		return models.Board{}, card, gorm.ErrRecordNotFound
+	}
+
+	var board models.Board
+	err := config.DB.Preload("Members").Where("id = ?", boardID).First(&board).Error
+	return board, card, err
+}
+
+// ruleMatches reports whether the card passes the rule's list filter and conditions.
+// Labels and Assignees must be loaded.
+func ruleMatches(rule models.AutomationRule, card models.Card) bool {
+	if rule.Trigger.ListID != "" && rule.Trigger.ListID != card.ListID {
+		return false
+	}
+
+	for _, condition := range rule.Conditions {
+		var holds bool
+		switch condition.Type {
+		case models.ConditionLabel:
+			holds = slices.ContainsFunc(card.Labels, func(l models.Label) bool { return l.ID == condition.ID })
+		case models.ConditionList:
+			holds = card.ListID == condition.ID
+		case models.ConditionAssignee:
+			holds = slices.ContainsFunc(card.Assignees, func(u models.User) bool { return u.ID == condition.ID })
+		}
+		if holds == condition.Not {
+			return false
+		}
+	}
+	return true
+}
+
+// applyRule makes all of a rule's changes to the card in one transaction, so a
+// failing action leaves the card untouched. It returns the events to publish.
+func applyRule(rule models.AutomationRule, board models.Board, card models.Card) ([]pendingEvent, error) {
+	var pending []pendingEvent
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the card, and the lists it may move to, and pick up any change
+		// made since it was loaded
+		var moveTo []string
+		for _, action := range rule.Actions {
+			if action.Type == models.RuleActionMove {
+				moveTo = append(moveTo, action.ListID)
+			}
+		}
+		locked, err := lockCard(tx, card.ID, moveTo...)
+		if err != nil {
+			return err
+		}
+		locked.Labels, locked.Assignees = card.Labels, card.Assignees
+		card = locked
+
+		pending = nil
+		for _, action := range rule.Actions {
+			produced, err := applyAction(tx, rule, board, &card, action)
+			if err != nil {
+				return fmt.Errorf("%s: %w", action.Type, err)
+			}
+			pending = append(pending, produced...)
+		}
+		return nil
+	})
+	return pending, err
+}
+
+// applyAction makes one change to the card as the rule's creator. Changes that
+// are already in place are skipped.
+func applyAction(tx *gorm.DB, rule models.AutomationRule, board models.Board, card *models.Card, action models.RuleAction) ([]pendingEvent, error) {
+	before := *card
+	entry := activity.Entry{BoardID: board.ID, ActorID: rule.CreatorID, EntityType: models.EntityCard, EntityID: card.ID}
+
+	switch action.Type {
+	case models.RuleActionMove:
+		if card.ListID == action.ListID {
+			return nil, nil
+		}
+		var list models.List
+		if err := tx.Where("id = ? AND board_id = ?", action.ListID, board.ID).First(&list).Error; err != nil {
+			return nil, errors.New("list not found")
+		}
+		if err := moveCard(tx, card, list.ID, math.MaxInt32); err != nil {
+			return nil, err
+		}
+		card.Version++
+		if err := tx.Model(&models.Card{ID: card.ID}).Updates(map[string]interface{}{
+			"list_id": card.ListID, "rank": card.Rank, "version": card.Version,
+		}).Error; err != nil {
+			return nil, err
+		}
+		entry.Action, entry.Before, entry.After = activity.ActionMoved, activity.Card(before), activity.Card(*card)
+		if err := activity.Record(tx, entry); err != nil {
+			return nil, err
+		}
+		return []pendingEvent{{events.CardMoved, ruleCardPayload(*card)}}, nil
+
+	case models.RuleActionAssign:
+		if slices.ContainsFunc(card.Assignees, func(u models.User) bool { return u.ID == action.UserID }) {
+			return nil, nil
+		}
+		if !isBoardMember(board, action.UserID) {
+			return nil, errNotBoardMember
+		}
+		var user models.User
+		if err := tx.Where("id = ?", action.UserID).First(&user).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Model(&models.Card{ID: card.ID}).Association("Assignees").Append(&user); err != nil {
+			return nil, err
+		}
+		if err := subscriptions.Watch(tx, action.UserID, models.EntityCard, card.ID); err != nil {
+			return nil, err
+		}
+		entry.Action, entry.After = activity.ActionAssigneeAdded, activity.Member(user)
+		if err := activity.Record(tx, entry); err != nil {
+			return nil, err
+		}
+		card.Assignees = append(card.Assignees, user)
+		return []pendingEvent{{events.CardAssigned, gin.H{
+			"cardId": card.ID, "listId": card.ListID, "title": card.Title, "userId": action.UserID,
+		}}}, nil
+
+	case models.RuleActionUnassign:
+		removed := card.Assignees
+		if action.UserID != "" {
+			removed = slices.DeleteFunc(slices.Clone(card.Assignees), func(u models.User) bool { return u.ID != action.UserID })
+		}
+		if len(removed) == 0 {
+			return nil, nil
+		}
+		if err := tx.Model(&models.Card{ID: card.ID}).Association("Assignees").Delete(removed); err != nil {
+			return nil, err
+		}
+		entry.Action = activity.ActionAssigneeRemoved
+		for _, user := range removed {
+			entry.Before = activity.Member(user)
+			if err := activity.Record(tx, entry); err != nil {
+				return nil, err
+			}
+		}
+		if action.UserID == "" {
+			card.Assignees = nil
+		} else {
+			card.Assignees = slices.DeleteFunc(card.Assignees, func(u models.User) bool { return u.ID == action.UserID })
+		}
+		return []pendingEvent{{events.CardUpdated, ruleCardPayload(*card)}}, nil
+
+	case models.RuleActionAddLabel:
+		if slices.ContainsFunc(card.Labels, func(l models.Label) bool { return l.ID == action.LabelID }) {
+			return nil, nil
+		}
+		var label models.Label
+		if err := tx.Where("id = ? AND board_id = ?", action.LabelID, board.ID).First(&label).Error; err != nil {
+			return nil, errors.New("label not found")
+		}
+		if err := tx.Model(&models.Card{ID: card.ID}).Association("Labels").Append(&label); err != nil {
+			return nil, err
+		}
+		entry.Action, entry.After = activity.ActionLabelAdded, activity.Label(label)
+		if err := activity.Record(tx, entry); err != nil {
+			return nil, err
+		}
+		card.Labels = append(card.Labels, label)
+		return []pendingEvent{{events.LabelAdded, gin.H{
+			"cardId": card.ID, "listId": card.ListID, "title": card.Title, "labelId": label.ID,
+		}}}, nil
+
+	case models.RuleActionComment:
+		comment := models.Comment{ID: uuid.NewString(), CardID: card.ID, AuthorID: rule.CreatorID, Body: action.Text}
+		if err := tx.Omit("Card", "Author").Create(&comment).Error; err != nil {
+			return nil, err
+		}
+		mentioned, err := memberMentions(tx, board, comment.Body)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := mentions.Sync(tx, card.ID, &comment.ID, mentioned); err != nil {
+			return nil, err
+		}
+		entry.Action, entry.After = activity.ActionCommentAdded, activity.Comment(comment)
+		if err := activity.Record(tx, entry); err != nil {
+			return nil, err
+		}
+		produced := []pendingEvent{{events.CommentCreated, gin.H{
+			"id": comment.ID, "cardId": card.ID, "listId": card.ListID, "cardTitle": card.Title, "body": comment.Body,
+			"mentioned": userIDs(mentioned),
+		}}}
+		if len(mentioned) > 0 {
+			produced = append(produced, pendingEvent{events.MentionCreated, gin.H{
+				"cardId": card.ID, "listId": card.ListID, "cardTitle": card.Title, "commentId": comment.ID,
+				"mentioned": userIDs(mentioned),
+			}})
+		}
+		return produced, nil
+
+	case models.RuleActionArchive:
+		if card.ArchivedAt != nil {
+			return nil, nil
+		}
+		card.ArchivedAt = archivedAt(true)
+		card.Version++
+		if err := tx.Model(&models.Card{ID: card.ID}).Updates(map[string]interface{}{
+			"archived_at": card.ArchivedAt, "version": card.Version,
+		}).Error; err != nil {
+			return nil, err
+		}
+		entry.Action = activity.ActionArchived
+		if err := activity.Record(tx, entry); err != nil {
+			return nil, err
+		}
+		return []pendingEvent{{events.CardUpdated, ruleCardPayload(*card)}}, nil
+
+	case models.RuleActionCompleteDue:
+		if card.DueDate == nil || card.DueComplete {
+			return nil, nil
+		}
+		card.DueComplete = true
+		card.Version++
+		if err := tx.Model(&models.Card{ID: card.ID}).Updates(map[string]interface{}{
+			"due_complete": true, "version": card.Version,
+		}).Error; err != nil {
+			return nil, err
+		}
+		entry.Action, entry.Before, entry.After = activity.ActionUpdated, activity.Card(before), activity.Card(*card)
+		if err := activity.Record(tx, entry); err != nil {
+			return nil, err
+		}
+		return []pendingEvent{{events.CardUpdated, ruleCardPayload(*card)}}, nil
+	}
+	return nil, errors.New("unknown action")
+}
+
+// ruleCardPayload is the card as published in events, without the associations
+// loaded for the rule's conditions
+func ruleCardPayload(card models.Card) models.Card {
+	card.List = models.List{}
+	card.Labels = nil
+	card.Assignees = nil
+	card.Warnings = nil
+	return card
+}
+
+// describeActions says in words what each action does, for the execution log
+func describeActions(actions []models.RuleAction) []string {
+	descriptions := make([]string, len(actions))
+	for i, action := range actions {
+		descriptions[i] = describeAction(action)
+	}
+	return descriptions
+}
+
+func describeAction(action models.RuleAction) string {
+	switch action.Type {
+	case models.RuleActionMove:
+		var list models.List
+		name := action.ListID
+		if config.DB.Unscoped().Where("id = ?", action.ListID).First(&list).Error == nil {
+			name = list.Name
+		}
+		return fmt.Sprintf("Move to list %q", name)
+	case models.RuleActionAssign, models.RuleActionUnassign:
+		if action.Type == models.RuleActionUnassign && action.UserID == "" {
+			return "Remove all assignees"
+		}
+		var user models.User
+		name := action.UserID
+		if config.DB.Where("id = ?", action.UserID).First(&user).Error == nil {
+			name = user.Username
+		}
+		if action.Type == models.RuleActionAssign {
+			return "Assign @" + name
+		}
+		return "Unassign @" + name
+	case models.RuleActionAddLabel:
+		var label models.Label
+		name := action.LabelID
+		if config.DB.Where("id = ?", action.LabelID).First(&label).Error == nil {
+			name = label.Name
+		}
+		return fmt.Sprintf("Add label %q", name)
+	case models.RuleActionComment:
+		return fmt.Sprintf("Comment %q", action.Text)
+	case models.RuleActionArchive:
+		return "Archive the card"
+	case models.RuleActionCompleteDue:
+		return "Mark the due date complete"
+	}
+	return action.Type
+}
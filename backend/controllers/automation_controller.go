@@ -0,0 +1,346 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxRuleActions caps how much a single rule can do
+const maxRuleActions = 20
+
+// ruleInput is the body of a request that creates or replaces a rule
+type ruleInput struct {
+	Name       string                 `json:"name"`
+	Trigger    models.RuleTrigger     `json:"trigger"`
+	Conditions []models.RuleCondition `json:"conditions"`
+	Actions    []models.RuleAction    `json:"actions"`
+	Enabled    *bool                  `json:"enabled"`
+	DryRun     *bool                  `json:"dryRun"`
+}
+
+// GetAutomationRules lists a board's automation rules
+func GetAutomationRules(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var rules []models.AutomationRule
+	if err := config.DB.Preload("Creator").Where("board_id = ?", board.ID).
+		Order("created_at, id").Find(&rules).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get automation rules"})
+		return
+	}
+
+	response := make([]models.AutomationRuleResponse, len(rules))
+	for i, rule := range rules {
+		response[i] = ruleResponse(rule)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateAutomationRule adds a rule to a board. Rules are enabled unless the
+// request says otherwise, and make their changes as the user who created them.
+func CreateAutomationRule(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var input ruleInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if err := validateRule(board, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	rule := models.AutomationRule{
+		ID:         uuid.NewString(),
+		BoardID:    board.ID,
+		CreatorID:  userID.(string),
+		Name:       strings.TrimSpace(input.Name),
+		Trigger:    input.Trigger,
+		Conditions: input.Conditions,
+		Actions:    input.Actions,
+		Enabled:    input.Enabled == nil || *input.Enabled,
+		DryRun:     input.DryRun != nil && *input.DryRun,
+	}
+	if err := config.DB.Omit("Board", "Creator").Create(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create automation rule"})
+		return
+	}
+
+	config.DB.Where("id = ?", rule.CreatorID).First(&rule.Creator)
+	c.JSON(http.StatusCreated, ruleResponse(rule))
+}
+
+// UpdateAutomationRule replaces a rule's name, trigger, conditions and actions.
+// Enabled and dryRun keep their value when left out.
+func UpdateAutomationRule(c *gin.Context) {
+	board, rule, ok := findAutomationRule(c)
+	if !ok {
+		return
+	}
+
+	var input ruleInput
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if err := validateRule(board, input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule.Name = strings.TrimSpace(input.Name)
+	rule.Trigger = input.Trigger
+	rule.Conditions = input.Conditions
+	rule.Actions = input.Actions
+	if input.Enabled != nil {
+		rule.Enabled = *input.Enabled
+	}
+	if input.DryRun != nil {
+		rule.DryRun = *input.DryRun
+	}
+
+	if err := config.DB.Model(&rule).Select("name", "trigger", "conditions", "actions", "enabled", "dry_run", "updated_at").
+		Updates(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update automation rule"})
+		return
+	}
+	c.JSON(http.StatusOK, ruleResponse(rule))
+}
+
+// DeleteAutomationRule removes a rule along with its runs in the execution log
+func DeleteAutomationRule(c *gin.Context) {
+	_, rule, ok := findAutomationRule(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&rule).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete automation rule"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Automation rule deleted successfully"})
+}
+
+// TestAutomationRule is a dry run of a rule against one card: it reports whether
+// the card passes the rule's list filter and conditions and what the rule would
+// do, without changing anything or writing to the execution log
+func TestAutomationRule(c *gin.Context) {
+	board, rule, ok := findAutomationRule(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		CardID string `json:"cardId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	_, card, err := loadRuleCard(board.ID, input.CardID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	matches := ruleMatches(rule, card)
+	actions := []string{}
+	if matches {
+		actions = describeActions(rule.Actions)
+	}
+	c.JSON(http.StatusOK, gin.H{"matches": matches, "actions": actions})
+}
+
+// GetAutomationRuns returns a page of a board's execution log, newest first.
+// "ruleId" and "status" narrow it to one rule or outcome.
+func GetAutomationRuns(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Preload("Rule").Where("board_id = ?", board.ID)
+	if ruleID := c.Query("ruleId"); ruleID != "" {
+		query = query.Where("rule_id = ?", ruleID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if before := c.Query("before"); before != "" {
+		var cursor models.AutomationRun
+		if err := config.DB.Where("id = ? AND board_id = ?", before, board.ID).First(&cursor).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	// One extra row tells whether there is another page
+	var runs []models.AutomationRun
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get automation runs"})
+		return
+	}
+
+	page := models.AutomationRunPage{Runs: []models.AutomationRunResponse{}}
+	if len(runs) > limit {
+		runs = runs[:limit]
+		page.NextCursor = runs[limit-1].ID
+	}
+	for _, run := range runs {
+		actions := run.Actions
+		if actions == nil {
+			actions = []string{}
+		}
+		page.Runs = append(page.Runs, models.AutomationRunResponse{
+			ID:        run.ID,
+			RuleID:    run.RuleID,
+			RuleName:  run.Rule.Name,
+			CardID:    run.CardID,
+			EventType: run.EventType,
+			Status:    run.Status,
+			Actions:   actions,
+			Error:     run.Error,
+			CreatedAt: run.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// findAutomationRule loads the rule in the path if the current user can access its board
+func findAutomationRule(c *gin.Context) (models.Board, models.AutomationRule, bool) {
+	var rule models.AutomationRule
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return board, rule, false
+	}
+
+	if err := config.DB.Preload("Creator").Where("id = ? AND board_id = ?", c.Param("ruleId"), board.ID).
+		First(&rule).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Automation rule not found"})
+		return board, rule, false
+	}
+	return board, rule, true
+}
+
+// validateRule checks a rule's parts and that every list, label and user it
+// names belongs to the board. The board's Members must be loaded.
+func validateRule(board models.Board, input ruleInput) error {
+	trigger := input.Trigger
+	switch trigger.Type {
+	case models.TriggerCardCreated, models.TriggerCardMoved, models.TriggerDueDate, models.TriggerLabelAdded:
+	default:
+		return fmt.Errorf("unknown trigger: %q", trigger.Type)
+	}
+	if trigger.ListID != "" && !boardHasList(board.ID, trigger.ListID) {
+		return errors.New("trigger list not found on this board")
+	}
+	if trigger.LabelID != "" {
+		if trigger.Type != models.TriggerLabelAdded {
+			return errors.New("trigger labelId only applies to label.added")
+		}
+		if !boardHasLabel(board.ID, trigger.LabelID) {
+			return errors.New("trigger label not found on this board")
+		}
+	}
+
+	for _, condition := range input.Conditions {
+		switch condition.Type {
+		case models.ConditionLabel:
+			if !boardHasLabel(board.ID, condition.ID) {
+				return errors.New("condition label not found on this board")
+			}
+		case models.ConditionList:
+			if !boardHasList(board.ID, condition.ID) {
+				return errors.New("condition list not found on this board")
+			}
+		case models.ConditionAssignee:
+			if !isBoardMember(board, condition.ID) {
+				return errors.New("condition assignee is not a member of this board")
+			}
+		default:
+			return fmt.Errorf("unknown condition: %q", condition.Type)
+		}
+	}
+
+	if len(input.Actions) == 0 || len(input.Actions) > maxRuleActions {
+		return fmt.Errorf("a rule needs between 1 and %d actions", maxRuleActions)
+	}
+	for _, action := range input.Actions {
+		switch action.Type {
+		case models.RuleActionMove:
+			if !boardHasList(board.ID, action.ListID) {
+				return errors.New("move action list not found on this board")
+			}
+		case models.RuleActionAssign:
+			if !isBoardMember(board, action.UserID) {
+				return errors.New("assign action user is not a member of this board")
+			}
+		case models.RuleActionAddLabel:
+			if !boardHasLabel(board.ID, action.LabelID) {
+				return errors.New("add_label action label not found on this board")
+			}
+		case models.RuleActionComment:
+			if strings.TrimSpace(action.Text) == "" {
+				return errors.New("comment action needs text")
+			}
+		case models.RuleActionUnassign, models.RuleActionArchive, models.RuleActionCompleteDue:
+		default:
+			return fmt.Errorf("unknown action: %q", action.Type)
+		}
+	}
+	return nil
+}
+
+func boardHasList(boardID, listID string) bool {
+	var count int64
+	config.DB.Model(&models.List{}).Where("id = ? AND board_id = ?", listID, boardID).Count(&count)
+	return count > 0
+}
+
+func boardHasLabel(boardID, labelID string) bool {
+	var count int64
+	config.DB.Model(&models.Label{}).Where("id = ? AND board_id = ?", labelID, boardID).Count(&count)
+	return count > 0
+}
+
+func ruleResponse(rule models.AutomationRule) models.AutomationRuleResponse {
+	conditions := rule.Conditions
+	if conditions == nil {
+		conditions = []models.RuleCondition{}
+	}
+	return models.AutomationRuleResponse{
+		ID:         rule.ID,
+		Name:       rule.Name,
+		Trigger:    rule.Trigger,
+		Conditions: conditions,
+		Actions:    rule.Actions,
+		Enabled:    rule.Enabled,
+		DryRun:     rule.DryRun,
+		Creator:    models.UserSummary{ID: rule.Creator.ID, Username: rule.Creator.Username},
+		CreatedAt:  rule.CreatedAt,
+		UpdatedAt:  rule.UpdatedAt,
+	}
+}
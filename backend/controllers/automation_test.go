@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"testing"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+)
+
+func TestRuleMatches(t *testing.T) {
+	card := models.Card{
+		ID:        "card",
+		ListID:    "todo",
+		Labels:    []models.Label{{ID: "urgent"}},
+		Assignees: []models.User{{ID: "bob"}},
+	}
+
+	tests := []struct {
+		name       string
+		trigger    models.RuleTrigger
+		conditions []models.RuleCondition
+		want       bool
+	}{
+		{"no conditions", models.RuleTrigger{}, nil, true},
+		{"trigger list", models.RuleTrigger{ListID: "todo"}, nil, true},
+		{"other trigger list", models.RuleTrigger{ListID: "done"}, nil, false},
+		{"has label", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionLabel, ID: "urgent"}}, true},
+		{"lacks label", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionLabel, ID: "later"}}, false},
+		{"not label it has", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionLabel, ID: "urgent", Not: true}}, false},
+		{"not label it lacks", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionLabel, ID: "later", Not: true}}, true},
+		{"in list", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionList, ID: "todo"}}, true},
+		{"not in list", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionList, ID: "todo", Not: true}}, false},
+		{"assigned", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionAssignee, ID: "bob"}}, true},
+		{"not assigned", models.RuleTrigger{}, []models.RuleCondition{{Type: models.ConditionAssignee, ID: "alice", Not: true}}, true},
+		{"every condition must hold", models.RuleTrigger{}, []models.RuleCondition{
+			{Type: models.ConditionLabel, ID: "urgent"},
+			{Type: models.ConditionAssignee, ID: "bob", Not: true},
+		}, false},
+	}
+	for _, test := range tests {
+		rule := models.AutomationRule{Trigger: test.trigger, Conditions: test.conditions}
+		if got := ruleMatches(rule, card); got != test.want {
+			t.Errorf("%s: ruleMatches = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// createRule saves a rule by alice on the fixture board that comments on cards
+func createRule(t *testing.T, f fixture, id, text string) models.AutomationRule {
+	t.Helper()
+	rule := models.AutomationRule{
+		ID:        id,
+		BoardID:   f.board.ID,
+		CreatorID: f.alice.ID,
+		Name:      id,
+		Trigger:   models.RuleTrigger{Type: models.TriggerCardCreated},
+		Actions:   []models.RuleAction{{Type: models.RuleActionComment, Text: text}},
+		Enabled:   true,
+	}
+	if err := config.DB.Omit("Board", "Creator").Create(&rule).Error; err != nil {
+		t.Fatalf("Failed to create rule: %v", err)
+	}
+	return rule
+}
+
+// lastRun returns the execution log entry of a rule, failing if there is none
+func lastRun(t *testing.T, ruleID string) models.AutomationRun {
+	t.Helper()
+	var run models.AutomationRun
+	if err := config.DB.Where("rule_id = ?", ruleID).Order("created_at DESC").First(&run).Error; err != nil {
+		t.Fatalf("No run logged for %s: %v", ruleID, err)
+	}
+	return run
+}
+
+func TestRuleChainIsCutOff(t *testing.T) {
+	f := openFixture(t)
+	rule := createRule(t, f, "rule", "Thanks")
+
+	tests := []struct {
+		name  string
+		chain []string
+	}{
+		{"rule already ran in the chain", []string{"other", rule.ID}},
+		{"chain too long", []string{"r1", "r2", "r3", "r4", "r5"}},
+	}
+	for _, test := range tests {
+		runRule(rule, events.Event{Type: models.TriggerCardCreated, BoardID: f.board.ID, Rules: test.chain}, f.card.ID)
+
+		if run := lastRun(t, rule.ID); run.Status != models.RunLoopPrevented {
+			t.Errorf("%s: status %q, want %q", test.name, run.Status, models.RunLoopPrevented)
+		}
+		config.DB.Where("rule_id = ?", rule.ID).Delete(&models.AutomationRun{})
+	}
+
+	var comments int64
+	config.DB.Model(&models.Comment{}).Count(&comments)
+	if comments != 0 {
+		t.Fatalf("%d comments, want none from a cut-off chain", comments)
+	}
+
+	// One rule short of the limit still runs
+	runRule(rule, events.Event{Type: models.TriggerCardCreated, BoardID: f.board.ID, Rules: []string{"r1", "r2", "r3", "r4"}}, f.card.ID)
+	if run := lastRun(t, rule.ID); run.Status != models.RunSucceeded {
+		t.Fatalf("status %q, want %q: %s", run.Status, models.RunSucceeded, run.Error)
+	}
+}
+
+func TestRuleCommentMentionsMembers(t *testing.T) {
+	f := openFixture(t)
+	carol := models.User{ID: "carol", Username: "carol", Email: "carol@example.com", Password: "x"}
+	if err := config.DB.Omit("Boards").Create(&carol).Error; err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	rule := createRule(t, f, "rule", "@bob and @carol, please take a look")
+
+	runRule(rule, events.Event{Type: models.TriggerCardCreated, BoardID: f.board.ID}, f.card.ID)
+	if run := lastRun(t, rule.ID); run.Status != models.RunSucceeded {
+		t.Fatalf("status %q, want %q: %s", run.Status, models.RunSucceeded, run.Error)
+	}
+
+	var mentions []models.Mention
+	config.DB.Find(&mentions)
+	if len(mentions) != 1 || mentions[0].UserID != f.bob.ID || mentions[0].CommentID == nil {
+		t.Fatalf("mentions %+v, want only bob in the comment; carol isn't on the board", mentions)
+	}
+}
+
+func TestRuleOfFormerMemberFails(t *testing.T) {
+	f := openFixture(t)
+	rule := createRule(t, f, "rule", "Thanks")
+	rule.CreatorID = f.bob.ID
+	rule.DryRun = true
+	config.DB.Model(&rule).Updates(map[string]interface{}{"creator_id": rule.CreatorID, "dry_run": true})
+	if err := config.DB.Model(&f.board).Association("Members").Clear(); err != nil {
+		t.Fatalf("Failed to remove bob: %v", err)
+	}
+
+	runRule(rule, events.Event{Type: models.TriggerCardCreated, BoardID: f.board.ID}, f.card.ID)
+	if run := lastRun(t, rule.ID); run.Status != models.RunFailed {
+		t.Fatalf("dry run of a former member's rule: status %q, want %q", run.Status, models.RunFailed)
+	}
+}
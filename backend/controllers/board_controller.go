@@ -2,23 +2,36 @@ package controllers
 
 import (
 	"net/http"
+	"slices"
+	"time"
+	"trello-backend/activity"
 	"trello-backend/config"
+	"trello-backend/events"
 	"trello-backend/models"
+	"trello-backend/subscriptions"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 // CreateBoard membuat board baru
 func CreateBoard(c *gin.Context) {
 	var input struct {
-		Name string `json:"name" binding:"required"`
+		Name       string            `json:"name"`
+		TemplateID string            `json:"templateId"`
+		Variables  map[string]string `json:"variables"` // placeholder values for the template
 	}
-	if err := c.ShouldBindJSON(&input); err != nil {
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Name == "" && input.TemplateID == "") {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
+	if input.TemplateID != "" {
+		createBoardFromTemplate(c, input.TemplateID, input.Name, input.Variables)
+		return
+	}
+
 	userID, _ := c.Get("userID")
 
 	board := models.Board{
@@ -27,17 +40,35 @@ func CreateBoard(c *gin.Context) {
 		OwnerID: userID.(string),
 	}
 
-	if err := config.DB.Create(&board).Error; err != nil {
+	tx := config.DB.Begin()
+
+	if err := tx.Create(&board).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create board"})
 		return
 	}
 
 	// Automatically add the owner to the board's members
-	if err := config.DB.Model(&board).Association("Members").Append(&models.User{ID: userID.(string)}); err != nil {
+	if err := tx.Model(&board).Association("Members").Append(&models.User{ID: userID.(string)}); err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add owner to members"})
 		return
 	}
 
+	if err := logActivity(c, tx, activity.Entry{
+		BoardID: board.ID, Action: activity.ActionCreated,
+		EntityType: models.EntityBoard, EntityID: board.ID, After: activity.Board(board),
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
 	c.JSON(http.StatusCreated, board)
 }
 
@@ -53,22 +84,29 @@ type BoardMember struct {
 }
 
 type BoardResponse struct {
-	ID      string        `json:"id"`
-	Name    string        `json:"name"`
-	OwnerID string        `json:"ownerId"`
-	Owner   BoardOwner    `json:"owner"`
-	Members []BoardMember `json:"members"`
+	ID         string        `json:"id"`
+	Name       string        `json:"name"`
+	OwnerID    string        `json:"ownerId"`
+	Owner      BoardOwner    `json:"owner"`
+	Members    []BoardMember `json:"members"`
+	Archived   bool          `json:"archived"`
+	IsTemplate bool          `json:"isTemplate"`
 }
 
 // GetAllBoards mendapatkan semua boards milik pengguna
 func GetAllBoards(c *gin.Context) {
 	userID, _ := c.Get("userID")
 
+	query := config.DB.Preload("Owner").Preload("Members").
+		Joins("JOIN board_members ON boards.id = board_members.board_id").
+		Where("board_members.user_id = ?", userID)
+	if c.Query("includeArchived") != "true" {
+		query = query.Where("boards.archived_at IS NULL")
+	}
+
 	var boards []models.Board
 	// Preload Owner and Members relationships
-	if err := config.DB.Preload("Owner").Preload("Members").
-		Joins("JOIN board_members ON boards.id = board_members.board_id").
-		Where("board_members.user_id = ?", userID).Find(&boards).Error; err != nil {
+	if err := query.Find(&boards).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get boards"})
 		return
 	}
@@ -94,7 +132,9 @@ func GetAllBoards(c *gin.Context) {
 				Username: board.Owner.Username,
 				Email:    board.Owner.Email,
 			},
-			Members: members,
+			Members:    members,
+			Archived:   board.ArchivedAt != nil,
+			IsTemplate: board.IsTemplate,
 		}
 	}
 
@@ -164,12 +204,23 @@ func UpdateBoard(c *gin.Context) {
 		return
 	}
 
+	before := activity.Board(board)
 	board.Name = input.Name
-	if err := config.DB.Save(&board).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&board).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionUpdated,
+			EntityType: models.EntityBoard, EntityID: board.ID, Before: before, After: activity.Board(board),
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update board"})
 		return
 	}
 
+	publishEvent(c, board.ID, events.BoardUpdated, boardSummary(board))
 	c.JSON(http.StatusOK, board)
 }
 
@@ -190,25 +241,39 @@ func DeleteBoard(c *gin.Context) {
 		return
 	}
 
-	// Delete all lists and cards in the board
-	if err := config.DB.Where("list_id in (?)", board.ID).Delete(&models.Card{}).Error; err != nil {
+	// Move the board, its lists and their cards to the trash in one transaction and
+	// with the same timestamp, so restoring the board brings them back together.
+	// Members are kept for the restore; the foreign keys remove them when the board is purged.
+	deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+	tx := config.DB.Begin()
+
+	boardLists := tx.Model(&models.List{}).Select("id").Where("board_id = ?", board.ID)
+	if err := tx.Model(&models.Card{}).Where("list_id IN (?)", boardLists).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cards"})
 		return
 	}
-	if err := config.DB.Where("board_id = ?", board.ID).Delete(&models.List{}).Error; err != nil {
+	if err := tx.Model(&models.List{}).Where("board_id = ?", board.ID).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lists"})
 		return
 	}
-
-	// Delete all members from the board
-	if err := config.DB.Where("board_id = ?", board.ID).Delete(&models.BoardMember{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete members"})
+	if err := tx.Model(&board).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete board"})
+		return
+	}
+	if err := logActivity(c, tx, activity.Entry{
+		BoardID: board.ID, Action: activity.ActionDeleted,
+		EntityType: models.EntityBoard, EntityID: board.ID, Before: activity.Board(board),
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
 		return
 	}
 
-	// Delete the board
-	if err := config.DB.Where("id = ?", board.ID).Delete(&models.Board{}).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete board"})
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Board deleted successfully"})
@@ -239,51 +304,78 @@ func GetBoardWithLists(c *gin.Context) {
 		}
 	}
 
-	if !isOwner && !isMember {
+	// Built-in templates can be previewed by everyone
+	if !isOwner && !isMember && !board.BuiltIn {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
 		return
 	}
 
-	// Rest of the code remains the same
+	// Archived lists and cards are hidden unless explicitly requested
+	includeArchived := c.Query("includeArchived") == "true"
+
+	listQuery := config.DB.Where("board_id = ?", boardID)
+	if !includeArchived {
+		listQuery = listQuery.Where("archived_at IS NULL")
+	}
+
 	var lists []models.List
-	if err := config.DB.Where("board_id = ?", boardID).Order("position").Find(&lists).Error; err != nil {
+	if err := listQuery.Order("rank, id").Find(&lists).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get lists"})
 		return
 	}
 
 	// Build response
 	response := models.BoardFullResponse{
-		ID:    board.ID,
-		Name:  board.Name,
-		Lists: make([]models.ListResponse, 0),
+		ID:                board.ID,
+		Name:              board.Name,
+		Archived:          board.ArchivedAt != nil,
+		IsTemplate:        board.IsTemplate,
+		BlockedMovePolicy: board.BlockedMovePolicy,
+		WIPMode:           board.WIPMode,
+		Lists:             make([]models.ListResponse, 0),
+	}
+	now := time.Now().UTC()
+
+	details, err := loadCardDetails(board.ID, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get card details"})
+		return
 	}
 
-	for _, list := range lists {
+	for position, list := range lists {
 		// Get cards for this list
+		cardQuery := config.DB.Preload("Labels").Where("list_id = ?", list.ID)
+		if !includeArchived {
+			cardQuery = cardQuery.Where("archived_at IS NULL")
+		}
+
 		var cards []models.Card
-		if err := config.DB.Where("list_id = ?", list.ID).Order("position").Find(&cards).Error; err != nil {
+		if err := cardQuery.Order("rank, id").Find(&cards).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cards"})
 			return
 		}
 
 		// Build cards response
 		cardResponses := make([]models.CardResponse, len(cards))
+		cardCount := 0
 		for i, card := range cards {
-			cardResponses[i] = models.CardResponse{
-				ID:          card.ID,
-				Title:       card.Title,
-				Description: card.Description,
-				Position:    card.Position,
-				Deadline:    card.Deadline,
+			cardResponses[i] = newCardResponse(card, i, now, details)
+			if card.ArchivedAt == nil {
+				cardCount++
 			}
 		}
 
 		// Add list with its cards to response
 		response.Lists = append(response.Lists, models.ListResponse{
-			ID:       list.ID,
-			Name:     list.Name,
-			Position: list.Position,
-			Cards:    cardResponses,
+			ID:        list.ID,
+			Name:      list.Name,
+			Position:  position,
+			Rank:      list.Rank,
+			Archived:  list.ArchivedAt != nil,
+			IsDone:    list.IsDone,
+			CardCount: cardCount,
+			WIPLimit:  list.WIPLimit,
+			Cards:     cardResponses,
 		})
 	}
 
@@ -325,11 +417,23 @@ func AddBoardMember(c *gin.Context) {
 	}
 
 	// Add the user to the board's members
-	if err := config.DB.Model(&board).Association("Members").Append(&user); err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&board).Association("Members").Append(&user); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionMemberAdded,
+			EntityType: models.EntityBoard, EntityID: board.ID, After: activity.Member(user),
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add member to the board"})
 		return
 	}
 
+	if !slices.ContainsFunc(board.Members, func(u models.User) bool { return u.ID == user.ID }) {
+		publishEvent(c, board.ID, events.MemberAdded, gin.H{"userId": user.ID, "boardName": board.Name})
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Member added successfully"})
 }
 
@@ -381,6 +485,25 @@ func UpdateBoardMembers(c *gin.Context) {
 		return
 	}
 
+	// Log who joined and who left, compared with the members before the update
+	if err := logMemberChanges(c, tx, board.ID, board.Members, newMembers); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	// Whoever left stops watching the board and everything on it
+	for _, user := range board.Members {
+		if user.ID == board.OwnerID || slices.ContainsFunc(newMembers, func(u models.User) bool { return u.ID == user.ID }) {
+			continue
+		}
+		if err := subscriptions.UnwatchBoard(tx, user.ID, board.ID); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscriptions"})
+			return
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -388,6 +511,11 @@ func UpdateBoardMembers(c *gin.Context) {
 		return
 	}
 
+	for _, user := range newMembers {
+		if !slices.ContainsFunc(board.Members, func(u models.User) bool { return u.ID == user.ID }) {
+			publishEvent(c, board.ID, events.MemberAdded, gin.H{"userId": user.ID, "boardName": board.Name})
+		}
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Board members updated successfully"})
 }
 
@@ -410,10 +538,64 @@ func RemoveBoardMember(c *gin.Context) {
 	}
 
 	// Remove the user from the board's members
-	if err := config.DB.Model(&board).Association("Members").Delete(&user); err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&board).Association("Members").Delete(&user); err != nil {
+			return err
+		}
+		if user.ID != board.OwnerID {
+			if err := subscriptions.UnwatchBoard(tx, user.ID, board.ID); err != nil {
+				return err
+			}
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionMemberRemoved,
+			EntityType: models.EntityBoard, EntityID: board.ID, Before: activity.Member(user),
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member from the board"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Member removed successfully"})
 }
+
+// logMemberChanges records the members added and removed when a board's members
+// went from before to after
+func logMemberChanges(c *gin.Context, tx *gorm.DB, boardID string, before, after []models.User) error {
+	sameUser := func(id string) func(models.User) bool {
+		return func(u models.User) bool { return u.ID == id }
+	}
+
+	for _, user := range after {
+		if !slices.ContainsFunc(before, sameUser(user.ID)) {
+			if err := logActivity(c, tx, activity.Entry{
+				BoardID: boardID, Action: activity.ActionMemberAdded,
+				EntityType: models.EntityBoard, EntityID: boardID, After: activity.Member(user),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	for _, user := range before {
+		if !slices.ContainsFunc(after, sameUser(user.ID)) {
+			if err := logActivity(c, tx, activity.Entry{
+				BoardID: boardID, Action: activity.ActionMemberRemoved,
+				EntityType: models.EntityBoard, EntityID: boardID, Before: activity.Member(user),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func boardSummary(board models.Board) models.BoardSummary {
+	return models.BoardSummary{
+		ID:                board.ID,
+		Name:              board.Name,
+		IsTemplate:        board.IsTemplate,
+		BlockedMovePolicy: board.BlockedMovePolicy,
+		WIPMode:           board.WIPMode,
+	}
+}
@@ -1,12 +1,26 @@
 package controllers
 
 import (
+	"errors"
+	"log"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"trello-backend/activity"
 	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/mentions"
 	"trello-backend/models"
+	"trello-backend/rank"
+	"trello-backend/subscriptions"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateCard creates a new card in a list
@@ -15,17 +29,40 @@ func CreateCard(c *gin.Context) {
 
 	// Validate input
 	var input struct {
-		Title       string `json:"title" binding:"required"`
-		Description string `json:"description"`
-		Position    int    `json:"position"`
-		Deadline    string `json:"deadline"`
+		Title       string            `json:"title"`
+		Description string            `json:"description"`
+		Position    *int              `json:"position"` // index in the list, appended when omitted
+		StartDate   string            `json:"startDate"`
+		DueDate     string            `json:"dueDate"`
+		Deadline    string            `json:"deadline"` // legacy alias for dueDate
+		Timezone    string            `json:"timezone"`
+		TemplateID  string            `json:"templateId"`
+		Variables   map[string]string `json:"variables"` // placeholder values for the template
 	}
 
-	if err := c.ShouldBindJSON(&input); err != nil {
+	if err := c.ShouldBindJSON(&input); err != nil || (input.Title == "" && input.TemplateID == "") {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
 
+	if input.DueDate == "" {
+		input.DueDate = input.Deadline
+	}
+	startDate, err := parseCardDate(input.StartDate, input.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	dueDate, err := parseCardDate(input.DueDate, input.Timezone)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validateCardDates(startDate, dueDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Get the list and validate access
 	var list models.List
 	if err := config.DB.Where("id = ?", listID).First(&list).Error; err != nil {
@@ -56,21 +93,84 @@ func CreateCard(c *gin.Context) {
 		return
 	}
 
+	// Fields left empty are taken from the template, with placeholders filled in
+	var template *models.Card
+	if input.TemplateID != "" {
+		found, ok := findTemplateCard(c, input.TemplateID)
+		if !ok {
+			return
+		}
+		template = &found
+		fill := placeholderFiller(input.Variables)
+		if input.Title == "" {
+			input.Title = fill(found.Title)
+		}
+		if input.Description == "" {
+			input.Description = fill(found.Description)
+		}
+	}
+
+	mentioned, ok := mentionedMembers(c, list.BoardID, input.Description)
+	if !ok {
+		return
+	}
+
+	position := math.MaxInt32
+	if input.Position != nil {
+		position = *input.Position
+	}
+
 	// Create card
 	card := models.Card{
 		ID:          uuid.NewString(),
 		Title:       input.Title,
 		Description: input.Description,
-		Position:    input.Position,
 		ListID:      listID,
-		Deadline:    input.Deadline,
+		StartDate:   startDate,
+		DueDate:     dueDate,
 	}
 
-	if err := config.DB.Create(&card).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the list so concurrent creates can't overfill it or take the same rank
+		if err := lockLists(tx, list.ID); err != nil {
+			return err
+		}
+		key, err := rank.At(tx, rank.CardsIn(list.ID), position, "")
+		if err != nil {
+			return err
+		}
+		card.Rank = key
+		list.Board = board
+		if err := checkWIPLimit(tx, &card, list); err != nil {
+			return err
+		}
+		if err := tx.Create(&card).Error; err != nil {
+			return err
+		}
+		if _, err := mentions.Sync(tx, card.ID, nil, mentioned); err != nil {
+			return err
+		}
+		if template != nil {
+			if err := copyTemplateLabels(tx, *template, card.ID, list.BoardID); err != nil {
+				return err
+			}
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: list.BoardID, Action: activity.ActionCreated,
+			EntityType: models.EntityCard, EntityID: card.ID, After: activity.Card(card),
+		})
+	})
+	if errors.Is(err, errWIPLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": "List has reached its WIP limit"})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create card"})
 		return
 	}
 
+	publishEvent(c, list.BoardID, events.CardCreated, card)
+	publishMentions(c, list.BoardID, card, "", mentioned)
 	c.JSON(http.StatusCreated, card)
 }
 
@@ -97,23 +197,107 @@ func GetListCards(c *gin.Context) {
 		return
 	}
 
-	// Get cards
+	// Get cards, filtered and sorted by custom fields when asked
+	query, err := applyFieldQuery(c, config.DB.Preload("Labels").Where("list_id = ?", listID), board.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var cards []models.Card
-	if err := config.DB.Where("list_id = ?", listID).Order("position").Find(&cards).Error; err != nil {
+	if err := query.Find(&cards).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cards"})
 		return
 	}
 
-	c.JSON(http.StatusOK, cards)
+	details, err := loadCardDetails(board.ID, userID.(string))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get card details"})
+		return
+	}
+
+	now := time.Now().UTC()
+	response := make([]models.CardResponse, len(cards))
+	for i, card := range cards {
+		response[i] = newCardResponse(card, i, now, details)
+	}
+	c.JSON(http.StatusOK, response)
 }
 
-type CardResponse struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Position    int    `json:"position"`
-	ListID      string `json:"listId"`
-	Deadline    string `json:"deadline"`
+// cardDetails holds per-card data of a board that is stored outside the cards table
+type cardDetails struct {
+	fieldValues map[string][]models.CustomFieldValueResponse
+	blocks      map[string][]string
+	blockedBy   map[string][]string
+	watching    map[string]bool // cards the current user watches
+	mentions    map[string][]models.UserSummary
+}
+
+// loadCardDetails loads the details of all cards on a board, as seen by userID
+func loadCardDetails(boardID string, userID string) (cardDetails, error) {
+	var details cardDetails
+	var err error
+	if details.fieldValues, err = loadBoardFieldValues(boardID); err != nil {
+		return details, err
+	}
+	if details.blocks, details.blockedBy, err = loadBoardDependencies(boardID); err != nil {
+		return details, err
+	}
+	onBoard := config.DB.Table("cards").Select("cards.id").
+		Joins("JOIN lists ON lists.id = cards.list_id").Where("lists.board_id = ?", boardID)
+	if details.watching, err = subscriptions.Watched(config.DB, userID, models.EntityCard, onBoard); err != nil {
+		return details, err
+	}
+	if details.mentions, err = mentions.InDescriptions(config.DB, onBoard); err != nil {
+		return details, err
+	}
+	return details, nil
+}
+
+// newCardResponse maps a card at the given index of its list to its board view,
+// including computed due states and its details
+func newCardResponse(card models.Card, position int, now time.Time, details cardDetails) models.CardResponse {
+	fieldValues := details.fieldValues[card.ID]
+	if fieldValues == nil {
+		fieldValues = []models.CustomFieldValueResponse{}
+	}
+	mentioned := details.mentions[card.ID]
+	if mentioned == nil {
+		mentioned = []models.UserSummary{}
+	}
+	labels := make([]models.LabelResponse, len(card.Labels))
+	for i, label := range card.Labels {
+		labels[i] = newLabelResponse(label)
+	}
+	return models.CardResponse{
+		ID:           card.ID,
+		Title:        card.Title,
+		Description:  card.Description,
+		Position:     position,
+		Rank:         card.Rank,
+		StartDate:    card.StartDate,
+		DueDate:      card.DueDate,
+		DueComplete:  card.DueComplete,
+		Overdue:      card.IsOverdue(now),
+		DueSoon:      card.IsDueSoon(now),
+		Archived:     card.ArchivedAt != nil,
+		Version:      card.Version,
+		Labels:       labels,
+		IsTemplate:   card.IsTemplate,
+		CustomFields: fieldValues,
+		Blocks:       orEmpty(details.blocks[card.ID]),
+		BlockedBy:    orEmpty(details.blockedBy[card.ID]),
+		Watching:     details.watching[card.ID],
+		Mentions:     mentioned,
+	}
+}
+
+// orEmpty makes nil slices encode as [] rather than null
+func orEmpty(ids []string) []string {
+	if ids == nil {
+		return []string{}
+	}
+	return ids
 }
 
 // UpdateCard updates a specific card
@@ -121,10 +305,15 @@ func UpdateCard(c *gin.Context) {
 	cardID := c.Param("cardId")
 
 	var input struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
-		Position    int    `json:"position"`
-		NewListID   string `json:"newListId"`
+		Title       string  `json:"title"`
+		Description string  `json:"description"`
+		Position    int     `json:"position"`
+		NewListID   string  `json:"newListId"`
+		StartDate   *string `json:"startDate"`
+		DueDate     *string `json:"dueDate"`
+		DueComplete *bool   `json:"dueComplete"`
+		Timezone    string  `json:"timezone"`
+		Version     *int    `json:"version"`
 	}
 
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -132,124 +321,118 @@ func UpdateCard(c *gin.Context) {
 		return
 	}
 
-	tx := config.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	expectedVersion, err := requestedVersion(c, input.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Dates are only touched when sent; an empty string clears them
+	var startDate, dueDate *time.Time
+	if input.StartDate != nil {
+		parsed, err := parseCardDate(*input.StartDate, input.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-	}()
+		startDate = parsed
+	}
+	if input.DueDate != nil {
+		parsed, err := parseCardDate(*input.DueDate, input.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		dueDate = parsed
+	}
 
-	// Get current card
-	var card models.Card
-	if err := tx.Where("id = ?", cardID).First(&card).Error; err != nil {
-		tx.Rollback()
+	var current models.Card
+	if err := config.DB.Preload("List").Where("id = ?", cardID).First(&current).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
 		return
 	}
+	boardID := current.List.BoardID
 
-	originalListID := card.ListID
-	newPosition := input.Position
+	if _, ok := findMemberBoard(c, boardID); !ok {
+		return
+	}
 
-	if input.NewListID != "" && input.NewListID != originalListID {
-		// Moving to different list
-		// Validate new list exists
-		var newList models.List
-		if err := tx.Where("id = ?", input.NewListID).First(&newList).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusNotFound, gin.H{"error": "New list not found"})
+	var mentioned []models.User
+	if input.Description != "" {
+		var ok bool
+		if mentioned, ok = mentionedMembers(c, boardID, input.Description); !ok {
 			return
 		}
+	}
 
-		// Get cards in original list
-		var sourceCards []models.Card
-		if err := tx.Where("list_id = ?", originalListID).Order("position").Find(&sourceCards).Error; err != nil {
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get source cards"})
-			return
+			log.Printf("UpdateCard panic: %v", r)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
 		}
+	}()
 
-		// Get cards in destination list
-		var destCards []models.Card
-		if err := tx.Where("list_id = ?", input.NewListID).Order("position").Find(&destCards).Error; err != nil {
+	var moveTo []string
+	if input.NewListID != "" {
+		// Moving to another board goes through MoveCardToBoard, which remaps labels and assignees
+		var target models.List
+		if err := tx.Where("id = ?", input.NewListID).First(&target).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get destination cards"})
+			c.JSON(http.StatusNotFound, gin.H{"error": "New list not found"})
 			return
 		}
-
-		// Remove from source list
-		for i := card.Position + 1; i < len(sourceCards); i++ {
-			sourceCards[i].Position--
-			if err := tx.Save(&sourceCards[i]).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update source positions"})
-				return
-			}
-		}
-
-		// Validate new position
-		if newPosition < 0 {
-			newPosition = 0
-		}
-		if newPosition > len(destCards) {
-			newPosition = len(destCards)
-		}
-
-		// Make space in destination list
-		for i := len(destCards) - 1; i >= newPosition; i-- {
-			destCards[i].Position++
-			if err := tx.Save(&destCards[i]).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update destination positions"})
-				return
-			}
-		}
-
-		// Update card
-		card.ListID = input.NewListID
-		card.Position = newPosition
-	} else {
-		// Same list, just update position
-		var cards []models.Card
-		if err := tx.Where("list_id = ?", card.ListID).Order("position").Find(&cards).Error; err != nil {
+		if target.BoardID != boardID {
 			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cards"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New list is on another board; use the card move endpoint"})
 			return
 		}
+		moveTo = append(moveTo, target.ID)
+	}
 
-		oldPosition := card.Position
-		newPosition := input.Position
+	// Get current card and hold it until the transaction ends
+	card, err := lockCard(tx, cardID, moveTo...)
+	if errors.Is(err, errCardMoved) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Card was moved by someone else, try again"})
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
 
-		// Validate new position
-		if newPosition < 0 || newPosition >= len(cards) {
-			newPosition = len(cards) - 1
-		}
+	if expectedVersion != nil && *expectedVersion != card.Version {
+		tx.Rollback()
+		c.Header("ETag", cardETag(card))
+		c.JSON(http.StatusConflict, gin.H{"error": "Card was changed by someone else", "card": card})
+		return
+	}
+	before := card
 
-		// Update positions if changed
-		if oldPosition != newPosition {
-			// Remove card from old position
-			cards = append(cards[:oldPosition], cards[oldPosition+1:]...)
-
-			// Insert at new position
-			cards = append(cards[:newPosition], append([]models.Card{card}, cards[newPosition:]...)...)
-
-			// Update all positions
-			for i, card := range cards {
-				card.Position = i
-				if err := tx.Save(&card).Error; err != nil {
-					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update positions"})
-					return
-				}
-			}
+	targetListID := card.ListID
+	if len(moveTo) > 0 {
+		targetListID = moveTo[0]
+	}
 
-			// Update card position
-			card.Position = newPosition
-			if err := tx.Save(&card).Error; err != nil {
-				tx.Rollback()
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card position"})
-				return
-			}
+	if err := moveCard(tx, &card, targetListID, input.Position); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errCardBlocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Card is blocked by cards that are not done"})
+			return
+		}
+		if errors.Is(err, errWIPLimitReached) {
+			c.JSON(http.StatusConflict, gin.H{"error": "List has reached its WIP limit"})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "New list not found"})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card position"})
+		return
 	}
 
 	// Update other fields
@@ -259,17 +442,147 @@ func UpdateCard(c *gin.Context) {
 	if input.Description != "" {
 		card.Description = input.Description
 	}
+	if input.StartDate != nil {
+		card.StartDate = startDate
+	}
+	if input.DueDate != nil {
+		card.DueDate = dueDate
+	}
+	if input.DueComplete != nil {
+		card.DueComplete = *input.DueComplete
+	}
+	if err := validateCardDates(card.StartDate, card.DueDate); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
+	card.Version++
 	if err := tx.Save(&card).Error; err != nil {
 		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
 		return
 	}
+	// Only people who weren't mentioned before are told about it
+	var newlyMentioned []models.User
+	if input.Description != "" {
+		if newlyMentioned, err = mentions.Sync(tx, card.ID, nil, mentioned); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mentions"})
+			return
+		}
+	}
+	if err := logCardChange(c, tx, boardID, boardID, before, card); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
 
-	tx.Commit()
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+	publishEvent(c, boardID, cardEventType(before, card), card)
+	publishMentions(c, boardID, card, "", newlyMentioned)
+	c.Header("ETag", cardETag(card))
 	c.JSON(http.StatusOK, card)
 }
 
+// requestedVersion reads the card version a client based its change on, from the
+// If-Match header or the version field. It returns nil when neither is sent.
+func requestedVersion(c *gin.Context, bodyVersion *int) (*int, error) {
+	if header := c.GetHeader("If-Match"); header != "" {
+		version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(header, "W/"), `"`))
+		if err != nil {
+			return nil, errors.New("invalid If-Match header")
+		}
+		return &version, nil
+	}
+	return bodyVersion, nil
+}
+
+// cardETag is the entity tag for a card's current version
+func cardETag(card models.Card) string {
+	return `"` + strconv.Itoa(card.Version) + `"`
+}
+
+// moveCard gives card a rank that puts it at position in the target list. The card
+// must have been locked with lockCard, along with the target list, so concurrent
+// moves into the same lists queue up. Only the moved card gets a new rank; its
+// neighbours keep theirs. Moves into another list are checked against its WIP
+// limit and the card's blockers.
+func moveCard(tx *gorm.DB, card *models.Card, targetListID string, position int) error {
+	key, err := rank.At(tx, rank.CardsIn(targetListID), position, card.ID)
+	if err != nil {
+		return err
+	}
+	moved := card.ListID != targetListID
+	card.ListID = targetListID
+	card.Rank = key
+
+	if !moved {
+		return nil
+	}
+	var list models.List
+	if err := tx.Preload("Board").Where("id = ?", targetListID).First(&list).Error; err != nil {
+		return err
+	}
+	if err := checkWIPLimit(tx, card, list); err != nil {
+		return err
+	}
+	return checkBlockedMove(tx, card, list)
+}
+
+// errCardMoved means a card moved to another list while it was being locked
+var errCardMoved = errors.New("card moved to another list")
+
+// lockCard locks a card until tx ends, after locking its list and the other
+// lists it may move to. Lists are always locked before the cards in them, as
+// the rank rebalancer does, so the two can't deadlock. The card's list is read
+// without a lock first; if the card moves to yet another list before it is
+// locked, lockCard fails with errCardMoved rather than lock that list after the card.
+func lockCard(tx *gorm.DB, cardID string, listIDs ...string) (models.Card, error) {
+	var card models.Card
+	if err := tx.Select("list_id").Where("id = ?", cardID).First(&card).Error; err != nil {
+		return card, err
+	}
+	listIDs = append(listIDs, card.ListID)
+	if err := lockLists(tx, listIDs...); err != nil {
+		return card, err
+	}
+
+	card = models.Card{}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", cardID).First(&card).Error; err != nil {
+		return card, err
+	}
+	if !slices.Contains(listIDs, card.ListID) {
+		return card, errCardMoved
+	}
+	return card, nil
+}
+
+// lockLists takes row locks on the given lists in ID order, so two moves between
+// the same lists can't deadlock. It fails with gorm.ErrRecordNotFound if a list is missing.
+func lockLists(tx *gorm.DB, listIDs ...string) error {
+	ids := make([]string, 0, len(listIDs))
+	for _, id := range listIDs {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+
+	var lists []models.List
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).Order("id").Find(&lists).Error; err != nil {
+		return err
+	}
+	if len(lists) != len(ids) {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
 // DeleteCard deletes a specific card
 func DeleteCard(c *gin.Context) {
 	cardID := c.Param("cardId")
@@ -310,12 +623,22 @@ func DeleteCard(c *gin.Context) {
 		return
 	}
 
-	// Delete card
-	if err := config.DB.Delete(&card).Error; err != nil {
+	// Move card to the trash
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&card).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: list.BoardID, Action: activity.ActionDeleted,
+			EntityType: models.EntityCard, EntityID: card.ID, Before: activity.Card(card),
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete card"})
 		return
 	}
 
+	publishEvent(c, list.BoardID, events.CardDeleted, gin.H{"id": card.ID, "listId": card.ListID, "title": card.Title})
 	c.JSON(http.StatusOK, gin.H{"message": "Card deleted successfully"})
 }
 
@@ -351,5 +674,6 @@ func GetCardByID(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", cardETag(card))
 	c.JSON(http.StatusOK, card)
 }
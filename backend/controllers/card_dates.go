@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"errors"
+	"time"
+)
+
+// cardDateLayouts are the accepted formats for card start and due dates.
+// Layouts without an offset are interpreted in the caller's timezone.
+var cardDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02Z",
+	"2006-01-02",
+}
+
+// parseCardDate parses a date sent by a client and returns it in UTC.
+// An empty value clears the date and yields nil.
+func parseCardDate(value string, timezone string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, errors.New("invalid timezone")
+		}
+	}
+
+	for _, layout := range cardDateLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err == nil {
+			t = t.UTC()
+			return &t, nil
+		}
+	}
+	return nil, errors.New("invalid date: " + value)
+}
+
+// validateCardDates checks that a start date never falls after the due date
+func validateCardDates(start, due *time.Time) error {
+	if start != nil && due != nil && start.After(*due) {
+		return errors.New("startDate must be before dueDate")
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package controllers
+
+import (
+	"net/http"
+	"trello-backend/collab"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var socketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return config.IsAllowedOrigin(r.Header.Get("Origin"))
+	},
+}
+
+var collabHub = collab.NewHub(cardOnBoard, memberOfBoard)
+
+// BoardSocket opens the board's collaboration WebSocket, which shares who is
+// viewing the board and its cards, soft edit locks and typing indicators
+func BoardSocket(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	var user models.User
+	if err := config.DB.Where("id = ?", userID.(string)).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// The upgrader writes the error response itself when the handshake fails
+	conn, err := socketUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	collabHub.Serve(conn, board.ID, collab.User{ID: user.ID, Username: user.Username})
+}
+
+// cardOnBoard reports whether a card, archived or not, is on a board
+func cardOnBoard(boardID, cardID string) bool {
+	var count int64
+	config.DB.Model(&models.Card{}).Joins("JOIN lists ON lists.id = cards.list_id").
+		Where("cards.id = ? AND lists.board_id = ?", cardID, boardID).Count(&count)
+	return count > 0
+}
+
+// memberOfBoard reports whether a user can still access a board
+func memberOfBoard(boardID, userID string) bool {
+	var board models.Board
+	if err := config.DB.Preload("Members").Where("id = ?", boardID).First(&board).Error; err != nil {
+		return false
+	}
+	return isBoardMember(board, userID)
+}
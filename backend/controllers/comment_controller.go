@@ -0,0 +1,226 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/mentions"
+	"trello-backend/models"
+	"trello-backend/subscriptions"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetCardComments lists the comments on a card, oldest first
+func GetCardComments(c *gin.Context) {
+	card, ok := findMemberCard(c)
+	if !ok {
+		return
+	}
+
+	var comments []models.Comment
+	if err := config.DB.Preload("Author").Where("card_id = ?", card.ID).
+		Order("created_at, id").Find(&comments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comments"})
+		return
+	}
+
+	mentioned, err := mentions.InComments(config.DB, card.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get comments"})
+		return
+	}
+
+	response := make([]models.CommentResponse, len(comments))
+	for i, comment := range comments {
+		response[i] = newCommentResponse(comment, mentioned[comment.ID])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateComment adds a comment to a card. The author starts watching the card.
+func CreateComment(c *gin.Context) {
+	var input struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	card, ok := findMemberCard(c)
+	if !ok {
+		return
+	}
+
+	mentioned, ok := mentionedMembers(c, card.List.BoardID, input.Body)
+	if !ok {
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	comment := models.Comment{
+		ID:       uuid.NewString(),
+		CardID:   card.ID,
+		AuthorID: userID.(string),
+		Body:     input.Body,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Card", "Author").Create(&comment).Error; err != nil {
+			return err
+		}
+		if _, err := mentions.Sync(tx, card.ID, &comment.ID, mentioned); err != nil {
+			return err
+		}
+		if err := logActivity(c, tx, activity.Entry{
+			BoardID: card.List.BoardID, Action: activity.ActionCommentAdded,
+			EntityType: models.EntityCard, EntityID: card.ID, After: activity.Comment(comment),
+		}); err != nil {
+			return err
+		}
+		return subscriptions.Watch(tx, comment.AuthorID, models.EntityCard, card.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	config.DB.Where("id = ?", comment.AuthorID).First(&comment.Author)
+	publishEvent(c, card.List.BoardID, events.CommentCreated, gin.H{
+		"id": comment.ID, "cardId": card.ID, "listId": card.ListID, "cardTitle": card.Title, "body": comment.Body,
+		"mentioned": userIDs(mentioned),
+	})
+	publishMentions(c, card.List.BoardID, card, comment.ID, mentioned)
+	c.JSON(http.StatusCreated, newCommentResponse(comment, userSummaries(mentioned)))
+}
+
+// UpdateComment edits a comment; only its author may do so
+func UpdateComment(c *gin.Context) {
+	var input struct {
+		Body string `json:"body" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || strings.TrimSpace(input.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	card, comment, ok := findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	mentioned, ok := mentionedMembers(c, card.List.BoardID, input.Body)
+	if !ok {
+		return
+	}
+
+	// Only people who weren't mentioned before are told about it
+	var newlyMentioned []models.User
+	before := activity.Comment(comment)
+	comment.Body = input.Body
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&comment).Update("body", comment.Body).Error; err != nil {
+			return err
+		}
+		var err error
+		newlyMentioned, err = mentions.Sync(tx, card.ID, &comment.ID, mentioned)
+		if err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: card.List.BoardID, Action: activity.ActionCommentUpdated,
+			EntityType: models.EntityCard, EntityID: card.ID, Before: before, After: activity.Comment(comment),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update comment"})
+		return
+	}
+
+	publishMentions(c, card.List.BoardID, card, comment.ID, newlyMentioned)
+	c.JSON(http.StatusOK, newCommentResponse(comment, userSummaries(mentioned)))
+}
+
+// DeleteComment removes a comment; only its author may do so
+func DeleteComment(c *gin.Context) {
+	card, comment, ok := findOwnComment(c)
+	if !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&comment).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: card.List.BoardID, Action: activity.ActionCommentDeleted,
+			EntityType: models.EntityCard, EntityID: card.ID, Before: activity.Comment(comment),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete comment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully"})
+}
+
+// findMemberCard loads the card in the path with its list, checking the current
+// user can access its board. It writes the error response and returns false otherwise.
+func findMemberCard(c *gin.Context) (models.Card, bool) {
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return card, false
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return card, false
+	}
+	return card, true
+}
+
+// findOwnComment loads the card and comment in the path if the current user wrote the comment
+func findOwnComment(c *gin.Context) (models.Card, models.Comment, bool) {
+	card, ok := findMemberCard(c)
+	if !ok {
+		return card, models.Comment{}, false
+	}
+
+	var comment models.Comment
+	if err := config.DB.Preload("Author").Where("id = ? AND card_id = ?", c.Param("commentId"), card.ID).
+		First(&comment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Comment not found"})
+		return card, comment, false
+	}
+
+	userID, _ := c.Get("userID")
+	if comment.AuthorID != userID.(string) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can change this comment"})
+		return card, comment, false
+	}
+	return card, comment, true
+}
+
+func newCommentResponse(comment models.Comment, mentioned []models.UserSummary) models.CommentResponse {
+	if mentioned == nil {
+		mentioned = []models.UserSummary{}
+	}
+	return models.CommentResponse{
+		ID:     comment.ID,
+		CardID: comment.CardID,
+		Author: models.UserSummary{
+			ID:       comment.Author.ID,
+			Username: comment.Author.Username,
+		},
+		Body:      comment.Body,
+		Mentions:  mentioned,
+		CreatedAt: comment.CreatedAt,
+		UpdatedAt: comment.UpdatedAt,
+	}
+}
@@ -0,0 +1,510 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"time"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+	"trello-backend/rank"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// copyBatchSize is how many rows are inserted per statement when copying
+const copyBatchSize = 500
+
+// copyOptions selects what is copied along with a board, list or card. Cards,
+// labels and attachments are copied unless turned off; members and assignees
+// only on request. Cards have no checklists to copy.
+type copyOptions struct {
+	IncludeCards       *bool `json:"includeCards"`
+	IncludeLabels      *bool `json:"includeLabels"`
+	IncludeAttachments *bool `json:"includeAttachments"`
+	IncludeMembers     *bool `json:"includeMembers"`
+	IncludeAssignees   *bool `json:"includeAssignees"`
+}
+
+func (o copyOptions) cards() bool       { return o.IncludeCards == nil || *o.IncludeCards }
+func (o copyOptions) labels() bool      { return o.IncludeLabels == nil || *o.IncludeLabels }
+func (o copyOptions) attachments() bool { return o.IncludeAttachments == nil || *o.IncludeAttachments }
+func (o copyOptions) members() bool     { return o.IncludeMembers != nil && *o.IncludeMembers }
+func (o copyOptions) assignees() bool   { return o.IncludeAssignees != nil && *o.IncludeAssignees }
+
+// bindCopyInput binds an optional JSON body; an empty body copies with the defaults
+func bindCopyInput(c *gin.Context, input interface{}) bool {
+	if err := c.ShouldBindJSON(input); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return false
+	}
+	return true
+}
+
+// CopyBoard makes a deep copy of a board, owned by the current user
+func CopyBoard(c *gin.Context) {
+	var input struct {
+		Name string `json:"name"`
+		copyOptions
+	}
+	if !bindCopyInput(c, &input) {
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+	userID, _ := c.Get("userID")
+
+	if input.Name == "" {
+		input.Name = board.Name + " (copy)"
+	}
+	copied := models.Board{
+		ID:      uuid.NewString(),
+		Name:    input.Name,
+		OwnerID: userID.(string),
+	}
+	var response models.CopyResponse
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+
+		// The owner is always a member; the others only when asked for
+		members := []models.BoardMember{{BoardID: copied.ID, UserID: copied.OwnerID}}
+		if input.members() {
+			for _, member := range board.Members {
+				if member.ID != copied.OwnerID {
+					members = append(members, models.BoardMember{BoardID: copied.ID, UserID: member.ID})
+				}
+			}
+		}
+		if err := tx.Create(&members).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if response, err = copyBoardInto(tx, board, copied, input.copyOptions, nil); err != nil {
+			return err
+		}
+		if err := dropNonMemberAssignees(tx, copied.ID, values(response.CardIDs)); err != nil {
+			return err
+		}
+		return logCopy(c, tx, copied.ID, models.EntityBoard, copied.ID, board.ID, activity.Board(copied))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy board"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// CopyBoardList copies a list and its cards to the end of this or another board
+func CopyBoardList(c *gin.Context) {
+	var input struct {
+		Name     string `json:"name"`
+		BoardID  string `json:"boardId"`
+		Position *int   `json:"position"`
+		copyOptions
+	}
+	if !bindCopyInput(c, &input) {
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var list models.List
+	if err := config.DB.Where("id = ? AND board_id = ?", c.Param("listId"), board.ID).First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	targetBoardID := board.ID
+	if input.BoardID != "" {
+		target, ok := findMemberBoard(c, input.BoardID)
+		if !ok {
+			return
+		}
+		targetBoardID = target.ID
+	}
+
+	if input.Name == "" {
+		input.Name = list.Name
+	}
+	position := math.MaxInt32
+	if input.Position != nil {
+		position = *input.Position
+	}
+
+	copied := models.List{
+		ID:       uuid.NewString(),
+		Name:     input.Name,
+		BoardID:  targetBoardID,
+		IsDone:   list.IsDone,
+		WIPLimit: list.WIPLimit,
+	}
+	response := models.CopyResponse{ID: copied.ID}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		key, err := rank.At(tx, rank.ListsIn(targetBoardID), position, "")
+		if err != nil {
+			return err
+		}
+		copied.Rank = key
+		if err := tx.Create(&copied).Error; err != nil {
+			return err
+		}
+		if err := logCopy(c, tx, targetBoardID, models.EntityList, copied.ID, list.ID, activity.List(copied)); err != nil {
+			return err
+		}
+
+		if !input.cards() {
+			return nil
+		}
+		var cards []models.Card
+		if err := tx.Where("list_id = ? AND archived_at IS NULL", list.ID).Find(&cards).Error; err != nil {
+			return err
+		}
+		cardIDs, err := copyCards(tx, cards, map[string]string{list.ID: copied.ID}, nil, nil, input.copyOptions)
+		if err != nil {
+			return err
+		}
+		response.CardIDs = cardIDs
+
+		if targetBoardID != board.ID {
+			return remapCardsToBoard(tx, targetBoardID, values(cardIDs))
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy list"})
+		return
+	}
+
+	publishEvent(c, targetBoardID, events.ListCreated, copied)
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// CopyCard copies a card into a list on this or another board
+func CopyCard(c *gin.Context) {
+	var input struct {
+		Title    string `json:"title"`
+		ListID   string `json:"listId"`
+		Position *int   `json:"position"`
+		copyOptions
+	}
+	if !bindCopyInput(c, &input) {
+		return
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	target := card.List
+	if input.ListID != "" {
+		if err := config.DB.Where("id = ?", input.ListID).First(&target).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target list not found"})
+			return
+		}
+		if _, ok := findMemberBoard(c, target.BoardID); !ok {
+			return
+		}
+	}
+
+	if input.Title != "" {
+		card.Title = input.Title
+	}
+	position := math.MaxInt32
+	if input.Position != nil {
+		position = *input.Position
+	}
+
+	var response models.CopyResponse
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		key, err := rank.At(tx, rank.CardsIn(target.ID), position, "")
+		if err != nil {
+			return err
+		}
+		card.Rank = key
+
+		cardIDs, err := copyCards(tx, []models.Card{card}, map[string]string{card.ListID: target.ID}, nil, nil, input.copyOptions)
+		if err != nil {
+			return err
+		}
+		response.ID = cardIDs[card.ID]
+
+		snapshot := activity.Card(card)
+		snapshot["listId"] = target.ID
+		if err := logCopy(c, tx, target.BoardID, models.EntityCard, response.ID, card.ID, snapshot); err != nil {
+			return err
+		}
+
+		if target.BoardID != card.List.BoardID {
+			return remapCardsToBoard(tx, target.BoardID, []string{response.ID})
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to copy card"})
+		return
+	}
+
+	var copied models.Card
+	if err := config.DB.Where("id = ?", response.ID).First(&copied).Error; err == nil {
+		publishEvent(c, target.BoardID, events.CardCreated, copied)
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// logCopy records the creation of a copy, noting the ID it was copied from
+func logCopy(c *gin.Context, tx *gorm.DB, boardID, entityType, entityID, sourceID string, after activity.Snapshot) error {
+	after["copiedFrom"] = sourceID
+	return logActivity(c, tx, activity.Entry{
+		BoardID: boardID, Action: activity.ActionCreated,
+		EntityType: entityType, EntityID: entityID, After: after,
+	})
+}
+
+// copyBoardInto copies the labels, custom fields, lists and cards of board into copied, which
+// must already exist. When fill is not nil it rewrites list names and card text.
+func copyBoardInto(tx *gorm.DB, board, copied models.Board, opts copyOptions, fill func(string) string) (models.CopyResponse, error) {
+	response := models.CopyResponse{ID: copied.ID}
+	if fill == nil {
+		fill = func(s string) string { return s }
+	}
+
+	var labelIDs map[string]string
+	if opts.labels() {
+		var labels []models.Label
+		if err := tx.Where("board_id = ?", board.ID).Find(&labels).Error; err != nil {
+			return response, err
+		}
+		labelIDs = make(map[string]string, len(labels))
+		for i := range labels {
+			labelIDs[labels[i].ID] = uuid.NewString()
+			labels[i].ID = labelIDs[labels[i].ID]
+			labels[i].BoardID = copied.ID
+		}
+		if len(labels) > 0 {
+			if err := tx.CreateInBatches(&labels, copyBatchSize).Error; err != nil {
+				return response, err
+			}
+		}
+		response.LabelIDs = labelIDs
+	}
+
+	var fields []models.CustomField
+	if err := tx.Where("board_id = ?", board.ID).Find(&fields).Error; err != nil {
+		return response, err
+	}
+	fieldIDs := make(map[string]string, len(fields))
+	for i := range fields {
+		fieldIDs[fields[i].ID] = uuid.NewString()
+		fields[i].ID = fieldIDs[fields[i].ID]
+		fields[i].BoardID = copied.ID
+	}
+	if len(fields) > 0 {
+		if err := tx.CreateInBatches(&fields, copyBatchSize).Error; err != nil {
+			return response, err
+		}
+	}
+
+	// Archived lists and cards are left behind
+	var lists []models.List
+	if err := tx.Where("board_id = ? AND archived_at IS NULL", board.ID).Find(&lists).Error; err != nil {
+		return response, err
+	}
+	listIDs := make(map[string]string, len(lists))
+	for i := range lists {
+		listIDs[lists[i].ID] = uuid.NewString()
+		lists[i].ID = listIDs[lists[i].ID]
+		lists[i].BoardID = copied.ID
+		lists[i].Name = fill(lists[i].Name)
+	}
+	if len(lists) > 0 {
+		if err := tx.CreateInBatches(&lists, copyBatchSize).Error; err != nil {
+			return response, err
+		}
+	}
+	response.ListIDs = listIDs
+
+	if !opts.cards() || len(lists) == 0 {
+		return response, nil
+	}
+	var cards []models.Card
+	if err := tx.Where("list_id IN ? AND archived_at IS NULL", keys(listIDs)).Find(&cards).Error; err != nil {
+		return response, err
+	}
+	for i := range cards {
+		cards[i].Title = fill(cards[i].Title)
+		cards[i].Description = fill(cards[i].Description)
+	}
+	cardIDs, err := copyCards(tx, cards, listIDs, labelIDs, fieldIDs, opts)
+	if err != nil {
+		return response, err
+	}
+	response.CardIDs = cardIDs
+	return response, nil
+}
+
+// copyCards inserts copies of cards into the lists mapped by listIDs, along with
+// their custom field values, the dependencies between them, and their labels and
+// assignees as selected by opts.
+// Labels and fields are mapped through labelIDs and fieldIDs, or kept as they are
+// when the map is nil. It returns the new card IDs keyed by the originals.
+func copyCards(tx *gorm.DB, cards []models.Card, listIDs, labelIDs, fieldIDs map[string]string, opts copyOptions) (map[string]string, error) {
+	cardIDs := make(map[string]string, len(cards))
+	if len(cards) == 0 {
+		return cardIDs, nil
+	}
+
+	copies := make([]models.Card, len(cards))
+	for i, card := range cards {
+		cardIDs[card.ID] = uuid.NewString()
+		copies[i] = models.Card{
+			ID:          cardIDs[card.ID],
+			Title:       card.Title,
+			Description: card.Description,
+			Rank:        card.Rank,
+			ListID:      listIDs[card.ListID],
+			StartDate:   card.StartDate,
+			DueDate:     card.DueDate,
+			DueComplete: card.DueComplete,
+		}
+	}
+	if err := tx.CreateInBatches(&copies, copyBatchSize).Error; err != nil {
+		return nil, err
+	}
+
+	if opts.labels() {
+		var links []models.CardLabel
+		if err := tx.Where("card_id IN ?", keys(cardIDs)).Find(&links).Error; err != nil {
+			return nil, err
+		}
+		copied := make([]models.CardLabel, 0, len(links))
+		for _, link := range links {
+			labelID := link.LabelID
+			if labelIDs != nil {
+				var ok bool
+				if labelID, ok = labelIDs[link.LabelID]; !ok {
+					continue
+				}
+			}
+			copied = append(copied, models.CardLabel{CardID: cardIDs[link.CardID], LabelID: labelID})
+		}
+		if len(copied) > 0 {
+			if err := tx.CreateInBatches(&copied, copyBatchSize).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var values []models.CustomFieldValue
+	if err := tx.Where("card_id IN ?", keys(cardIDs)).Find(&values).Error; err != nil {
+		return nil, err
+	}
+	for i := range values {
+		values[i].CardID = cardIDs[values[i].CardID]
+		if fieldIDs != nil {
+			values[i].FieldID = fieldIDs[values[i].FieldID]
+		}
+	}
+	if len(values) > 0 {
+		if err := tx.Omit(clause.Associations).CreateInBatches(&values, copyBatchSize).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// Dependencies are kept when both cards are copied
+	var dependencies []models.CardDependency
+	if err := tx.Where("blocking_card_id IN ? AND blocked_card_id IN ?", keys(cardIDs), keys(cardIDs)).
+		Find(&dependencies).Error; err != nil {
+		return nil, err
+	}
+	for i := range dependencies {
+		dependencies[i].BlockingCardID = cardIDs[dependencies[i].BlockingCardID]
+		dependencies[i].BlockedCardID = cardIDs[dependencies[i].BlockedCardID]
+	}
+	if len(dependencies) > 0 {
+		if err := tx.Omit(clause.Associations).CreateInBatches(&dependencies, copyBatchSize).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.attachments() {
+		if err := copyAttachments(tx, cardIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.assignees() {
+		var assignees []models.CardAssignee
+		if err := tx.Where("card_id IN ?", keys(cardIDs)).Find(&assignees).Error; err != nil {
+			return nil, err
+		}
+		for i := range assignees {
+			assignees[i].CardID = cardIDs[assignees[i].CardID]
+		}
+		if len(assignees) > 0 {
+			if err := tx.CreateInBatches(&assignees, copyBatchSize).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return cardIDs, nil
+}
+
+// copyAttachments copies the attachments of the cards in cardIDs, keyed by
+// original card ID, onto their copies. The file data is copied by the database
+// rather than read into memory.
+func copyAttachments(tx *gorm.DB, cardIDs map[string]string) error {
+	var attachments []models.Attachment
+	if err := tx.Select("id", "card_id").Where("card_id IN ?", keys(cardIDs)).Find(&attachments).Error; err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, attachment := range attachments {
+		if err := tx.Exec(`INSERT INTO attachments (id, card_id, uploader_id, filename, content_type, size, data, created_at)
+			SELECT ?, ?, uploader_id, filename, content_type, size, data, ? FROM attachments WHERE id = ?`,
+			uuid.NewString(), cardIDs[attachment.CardID], now, attachment.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
+
+func values(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for _, v := range m {
+		result = append(result, v)
+	}
+	return result
+}
@@ -0,0 +1,453 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fieldValueColumns is the custom_field_values column that holds each field type
+var fieldValueColumns = map[string]string{
+	models.FieldTypeText:     "text_value",
+	models.FieldTypeNumber:   "number_value",
+	models.FieldTypeDate:     "date_value",
+	models.FieldTypeCheckbox: "checked_value",
+	models.FieldTypeDropdown: "text_value",
+}
+
+// GetCustomFields returns the custom fields defined on a board
+func GetCustomFields(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var fields []models.CustomField
+	if err := config.DB.Where("board_id = ?", board.ID).Order("name").Find(&fields).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get custom fields"})
+		return
+	}
+
+	response := make([]models.CustomFieldResponse, len(fields))
+	for i, field := range fields {
+		response[i] = newCustomFieldResponse(field)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateCustomField adds a custom field to a board
+func CreateCustomField(c *gin.Context) {
+	var input struct {
+		Name    string   `json:"name" binding:"required"`
+		Type    string   `json:"type" binding:"required"`
+		Options []string `json:"options"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if _, ok := fieldValueColumns[input.Type]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of text, number, date, checkbox or dropdown"})
+		return
+	}
+	if input.Type == models.FieldTypeDropdown && len(input.Options) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A dropdown field needs options"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	field := models.CustomField{
+		ID:      uuid.NewString(),
+		BoardID: board.ID,
+		Name:    input.Name,
+		Type:    input.Type,
+	}
+	if field.Type == models.FieldTypeDropdown {
+		field.Options = input.Options
+	}
+	if err := config.DB.Create(&field).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create custom field"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newCustomFieldResponse(field))
+}
+
+// UpdateCustomField renames a custom field or changes its dropdown options.
+// Card values that use a removed option are cleared. The type can't be changed.
+func UpdateCustomField(c *gin.Context) {
+	var input struct {
+		Name    string   `json:"name"`
+		Options []string `json:"options"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var field models.CustomField
+	if err := config.DB.Where("id = ? AND board_id = ?", c.Param("fieldId"), board.ID).First(&field).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+
+	if input.Name != "" {
+		field.Name = input.Name
+	}
+	if input.Options != nil && field.Type == models.FieldTypeDropdown {
+		if len(input.Options) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "A dropdown field needs options"})
+			return
+		}
+		field.Options = input.Options
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&field).Error; err != nil {
+			return err
+		}
+		if field.Type != models.FieldTypeDropdown {
+			return nil
+		}
+		return tx.Where("field_id = ? AND text_value NOT IN ?", field.ID, field.Options).
+			Delete(&models.CustomFieldValue{}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update custom field"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newCustomFieldResponse(field))
+}
+
+// DeleteCustomField removes a custom field and its values from a board
+func DeleteCustomField(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	result := config.DB.Where("id = ? AND board_id = ?", c.Param("fieldId"), board.ID).Delete(&models.CustomField{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete custom field"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
+
+// SetCardFieldValue sets a card's value for a custom field. A null value clears it.
+func SetCardFieldValue(c *gin.Context) {
+	var input struct {
+		Value json.RawMessage `json:"value"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, card.List.BoardID)
+	if !ok {
+		return
+	}
+
+	var field models.CustomField
+	if err := config.DB.Where("id = ? AND board_id = ?", c.Param("fieldId"), board.ID).First(&field).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Custom field not found"})
+		return
+	}
+
+	// Anything but null is checked against the field's type
+	clearing := len(input.Value) == 0 || string(input.Value) == "null"
+	var value models.CustomFieldValue
+	if !clearing {
+		var err error
+		if value, err = parseFieldValue(field, input.Value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		value.CardID = card.ID
+		value.Field = field
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		before := activity.FieldValue(field, nil)
+		var current models.CustomFieldValue
+		result := tx.Where("card_id = ? AND field_id = ?", card.ID, field.ID).Limit(1).Find(&current)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			current.Field = field
+			before = activity.FieldValue(field, newFieldValueResponse(current).Value)
+		}
+
+		after := activity.FieldValue(field, nil)
+		if clearing {
+			if err := tx.Where("card_id = ? AND field_id = ?", card.ID, field.ID).
+				Delete(&models.CustomFieldValue{}).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{UpdateAll: true}).
+				Create(&value).Error; err != nil {
+				return err
+			}
+			after = activity.FieldValue(field, newFieldValueResponse(value).Value)
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionUpdated,
+			EntityType: models.EntityCard, EntityID: card.ID, Before: before, After: after,
+		})
+	})
+	if err != nil && clearing {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear value"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set value"})
+		return
+	}
+	if clearing {
+		c.JSON(http.StatusOK, gin.H{"message": "Value cleared successfully"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newFieldValueResponse(value))
+}
+
+func newCustomFieldResponse(field models.CustomField) models.CustomFieldResponse {
+	options := field.Options
+	if options == nil {
+		options = []string{}
+	}
+	return models.CustomFieldResponse{
+		ID:      field.ID,
+		Name:    field.Name,
+		Type:    field.Type,
+		Options: options,
+	}
+}
+
+// parseFieldValue validates a JSON value against the field's type
+func parseFieldValue(field models.CustomField, raw json.RawMessage) (models.CustomFieldValue, error) {
+	value := models.CustomFieldValue{FieldID: field.ID}
+
+	switch field.Type {
+	case models.FieldTypeText, models.FieldTypeDropdown:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return value, errors.New("value must be a string")
+		}
+		if field.Type == models.FieldTypeDropdown && !slices.Contains(field.Options, text) {
+			return value, errors.New("value must be one of the field's options")
+		}
+		value.TextValue = &text
+	case models.FieldTypeNumber:
+		var number float64
+		if err := json.Unmarshal(raw, &number); err != nil {
+			return value, errors.New("value must be a number")
+		}
+		value.NumberValue = &number
+	case models.FieldTypeDate:
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return value, errors.New("value must be a date")
+		}
+		date, err := parseCardDate(text, "")
+		if err != nil || date == nil {
+			return value, errors.New("value must be a date")
+		}
+		value.DateValue = date
+	case models.FieldTypeCheckbox:
+		var checked bool
+		if err := json.Unmarshal(raw, &checked); err != nil {
+			return value, errors.New("value must be true or false")
+		}
+		value.CheckedValue = &checked
+	}
+	return value, nil
+}
+
+// parseFieldFilter converts a value from a query string to the field's type
+func parseFieldFilter(field models.CustomField, text string) (interface{}, error) {
+	switch field.Type {
+	case models.FieldTypeNumber:
+		return strconv.ParseFloat(text, 64)
+	case models.FieldTypeDate:
+		date, err := parseCardDate(text, "")
+		if err != nil || date == nil {
+			return nil, errors.New("invalid date: " + text)
+		}
+		return *date, nil
+	case models.FieldTypeCheckbox:
+		return strconv.ParseBool(text)
+	}
+	return text, nil
+}
+
+// applyFieldQuery narrows a card query to cards matching the field[<fieldId>]=value
+// filters in the request and orders it by the sortField custom field, if any.
+// Cards without a value sort last.
+func applyFieldQuery(c *gin.Context, query *gorm.DB, boardID string) (*gorm.DB, error) {
+	filters := c.QueryMap("field")
+	sortField := c.Query("sortField")
+	if len(filters) == 0 && sortField == "" {
+		return query.Order("rank, id"), nil
+	}
+
+	var fields []models.CustomField
+	if err := config.DB.Where("board_id = ?", boardID).Find(&fields).Error; err != nil {
+		return nil, err
+	}
+	fieldsByID := make(map[string]models.CustomField, len(fields))
+	for _, field := range fields {
+		fieldsByID[field.ID] = field
+	}
+
+	for fieldID, text := range filters {
+		field, ok := fieldsByID[fieldID]
+		if !ok {
+			return nil, errors.New("unknown custom field: " + fieldID)
+		}
+		value, err := parseFieldFilter(field, text)
+		if err != nil {
+			return nil, err
+		}
+
+		// An unchecked checkbox matches cards that have no value as well
+		if value == false {
+			query = query.Where("cards.id NOT IN (?)", config.DB.Model(&models.CustomFieldValue{}).
+				Select("card_id").Where("field_id = ? AND checked_value = ?", field.ID, true))
+			continue
+		}
+		query = query.Where("cards.id IN (?)", config.DB.Model(&models.CustomFieldValue{}).
+			Select("card_id").Where("field_id = ? AND "+fieldValueColumns[field.Type]+" = ?", field.ID, value))
+	}
+
+	if sortField == "" {
+		return query.Order("rank, id"), nil
+	}
+	field, ok := fieldsByID[sortField]
+	if !ok {
+		return nil, errors.New("unknown custom field: " + sortField)
+	}
+	direction := "ASC"
+	if strings.EqualFold(c.Query("order"), "desc") {
+		direction = "DESC"
+	}
+	return query.Select("cards.*").
+		Joins("LEFT JOIN custom_field_values AS sort_value ON sort_value.card_id = cards.id AND sort_value.field_id = ?", field.ID).
+		Order("sort_value." + fieldValueColumns[field.Type] + " " + direction + " NULLS LAST, cards.rank, cards.id"), nil
+}
+
+// loadBoardFieldValues returns the custom field values of a board's cards, keyed by card ID
+func loadBoardFieldValues(boardID string) (map[string][]models.CustomFieldValueResponse, error) {
+	var values []models.CustomFieldValue
+	if err := config.DB.Preload("Field").
+		Joins("JOIN custom_fields ON custom_fields.id = custom_field_values.field_id").
+		Where("custom_fields.board_id = ?", boardID).
+		Order("custom_fields.name").Find(&values).Error; err != nil {
+		return nil, err
+	}
+
+	byCard := make(map[string][]models.CustomFieldValueResponse)
+	for _, value := range values {
+		byCard[value.CardID] = append(byCard[value.CardID], newFieldValueResponse(value))
+	}
+	return byCard, nil
+}
+
+// newFieldValueResponse maps a value with its Field loaded to its typed JSON form
+func newFieldValueResponse(value models.CustomFieldValue) models.CustomFieldValueResponse {
+	response := models.CustomFieldValueResponse{
+		FieldID: value.FieldID,
+		Name:    value.Field.Name,
+		Type:    value.Field.Type,
+	}
+	switch value.Field.Type {
+	case models.FieldTypeNumber:
+		response.Value = value.NumberValue
+	case models.FieldTypeDate:
+		response.Value = value.DateValue
+	case models.FieldTypeCheckbox:
+		response.Value = value.CheckedValue
+	default:
+		response.Value = value.TextValue
+	}
+	return response
+}
+
+// remapFieldValues moves the custom field values of cards that moved to another
+// board onto the target board's field with the same name and type. Values with no
+// matching field, or with a dropdown option the target field lacks, are dropped.
+func remapFieldValues(tx *gorm.DB, boardID string, cardIDs []string) error {
+	var values []models.CustomFieldValue
+	if err := tx.Preload("Field").Where("card_id IN ?", cardIDs).Find(&values).Error; err != nil {
+		return err
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	var fields []models.CustomField
+	if err := tx.Where("board_id = ?", boardID).Find(&fields).Error; err != nil {
+		return err
+	}
+	fieldByKey := make(map[string]models.CustomField, len(fields))
+	for _, field := range fields {
+		fieldByKey[strings.ToLower(field.Name)+"/"+field.Type] = field
+	}
+
+	if err := tx.Where("card_id IN ?", cardIDs).Delete(&models.CustomFieldValue{}).Error; err != nil {
+		return err
+	}
+	var remapped []models.CustomFieldValue
+	for _, value := range values {
+		field, ok := fieldByKey[strings.ToLower(value.Field.Name)+"/"+value.Field.Type]
+		if !ok {
+			continue
+		}
+		if field.Type == models.FieldTypeDropdown && (value.TextValue == nil || !slices.Contains(field.Options, *value.TextValue)) {
+			continue
+		}
+		value.FieldID = field.ID
+		value.Field = models.CustomField{}
+		remapped = append(remapped, value)
+	}
+	if len(remapped) == 0 {
+		return nil
+	}
+	return tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&remapped).Error
+}
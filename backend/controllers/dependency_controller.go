@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errCardBlocked is returned when a blocked card is moved into a done list on a
+// board whose policy forbids it
+var errCardBlocked = errors.New("card is blocked by cards that are not done")
+
+// errDependencyCycle is returned when a new dependency would make a card block itself
+var errDependencyCycle = errors.New("dependency would create a cycle")
+
+// AddCardDependency records that the card in the path blocks another card on the same board
+func AddCardDependency(c *gin.Context) {
+	var input struct {
+		CardID string `json:"cardId" binding:"required"` // the card being blocked
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var blocking models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&blocking).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, blocking.List.BoardID)
+	if !ok {
+		return
+	}
+
+	var blocked models.Card
+	if err := config.DB.Preload("List").Where("id = ?", input.CardID).First(&blocked).Error; err != nil ||
+		blocked.List.BoardID != board.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Blocked card must be on the same board"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the board so concurrent links can't form a cycle together
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", board.ID).First(&models.Board{}).Error; err != nil {
+			return err
+		}
+
+		cycle, err := blocksTransitively(tx, blocked.ID, blocking.ID)
+		if err != nil {
+			return err
+		}
+		if cycle || blocked.ID == blocking.ID {
+			return errDependencyCycle
+		}
+
+		dependency := models.CardDependency{BlockingCardID: blocking.ID, BlockedCardID: blocked.ID}
+		result := tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&dependency)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return logDependency(c, tx, activity.ActionDependencyAdded, board.ID, blocking.ID, blocked.ID)
+	})
+	if errors.Is(err, errDependencyCycle) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Dependency would create a cycle"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add dependency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency added successfully"})
+}
+
+// RemoveCardDependency removes the link between the card in the path and a card it blocks
+func RemoveCardDependency(c *gin.Context) {
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("blocking_card_id = ? AND blocked_card_id = ?", card.ID, c.Param("blockedCardId")).
+			Delete(&models.CardDependency{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return logDependency(c, tx, activity.ActionDependencyRemoved, card.List.BoardID, card.ID, c.Param("blockedCardId"))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove dependency"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Dependency removed successfully"})
+}
+
+// logDependency records a link between two cards being added or removed, in the
+// feeds of both cards
+func logDependency(c *gin.Context, tx *gorm.DB, action, boardID, blockingCardID, blockedCardID string) error {
+	entry := activity.Entry{BoardID: boardID, Action: action, EntityType: models.EntityCard}
+	if action == activity.ActionDependencyAdded {
+		entry.After = activity.Dependency(blockingCardID, blockedCardID)
+	} else {
+		entry.Before = activity.Dependency(blockingCardID, blockedCardID)
+	}
+	for _, cardID := range []string{blockingCardID, blockedCardID} {
+		entry.EntityID = cardID
+		if err := logActivity(c, tx, entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blocksTransitively reports whether from blocks to, directly or through other cards
+func blocksTransitively(tx *gorm.DB, from, to string) (bool, error) {
+	var found bool
+	err := tx.Raw(`WITH RECURSIVE chain(card_id) AS (
+			SELECT blocked_card_id FROM card_dependencies WHERE blocking_card_id = @from
+			UNION
+			SELECT d.blocked_card_id FROM card_dependencies d JOIN chain ON d.blocking_card_id = chain.card_id
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE card_id = @to)`,
+		map[string]interface{}{"from": from, "to": to}).Scan(&found).Error
+	return found, err
+}
+
+// loadBoardDependencies returns, per card of a board, the cards it blocks and the cards blocking it
+func loadBoardDependencies(boardID string) (map[string][]string, map[string][]string, error) {
+	var dependencies []models.CardDependency
+	if err := config.DB.
+		Joins("JOIN cards ON cards.id = card_dependencies.blocked_card_id").
+		Joins("JOIN lists ON lists.id = cards.list_id").
+		Where("lists.board_id = ?", boardID).
+		Find(&dependencies).Error; err != nil {
+		return nil, nil, err
+	}
+
+	blocks := make(map[string][]string)
+	blockedBy := make(map[string][]string)
+	for _, d := range dependencies {
+		blocks[d.BlockingCardID] = append(blocks[d.BlockingCardID], d.BlockedCardID)
+		blockedBy[d.BlockedCardID] = append(blockedBy[d.BlockedCardID], d.BlockingCardID)
+	}
+	return blocks, blockedBy, nil
+}
+
+// checkBlockedMove is called when card has moved into list, whose Board must be
+// loaded. If the list is done and the card still has blockers outside done lists,
+// it adds a warning to the card, or returns errCardBlocked when the board's policy
+// is "error". Blockers that are archived, or whose list is archived or in the
+// trash, count as resolved.
+func checkBlockedMove(tx *gorm.DB, card *models.Card, list models.List) error {
+	if !list.IsDone {
+		return nil
+	}
+
+	var open int64
+	if err := tx.Model(&models.CardDependency{}).
+		Joins("JOIN cards ON cards.id = card_dependencies.blocking_card_id").
+		Joins("JOIN lists ON lists.id = cards.list_id").
+		Where("card_dependencies.blocked_card_id = ? AND lists.is_done = ?", card.ID, false).
+		Where("cards.deleted_at IS NULL AND cards.archived_at IS NULL AND lists.deleted_at IS NULL AND lists.archived_at IS NULL").
+		Count(&open).Error; err != nil {
+		return err
+	}
+	if open == 0 {
+		return nil
+	}
+
+	if list.Board.BlockedMovePolicy == models.BlockedMoveError {
+		return errCardBlocked
+	}
+	card.Warnings = append(card.Warnings, fmt.Sprintf("Card is blocked by %d card(s) that are not done", open))
+	return nil
+}
+
+// dropCrossBoardDependencies removes the dependencies between cards moving to
+// another board and cards staying behind, since links only exist within a board
+func dropCrossBoardDependencies(tx *gorm.DB, boardID string, cardIDs []string) error {
+	onBoard := tx.Table("cards").Select("cards.id").
+		Joins("JOIN lists ON lists.id = cards.list_id").Where("lists.board_id = ?", boardID)
+	return tx.Where("blocking_card_id IN ? AND blocked_card_id NOT IN ? AND blocked_card_id NOT IN (?)", cardIDs, cardIDs, onBoard).
+		Or("blocked_card_id IN ? AND blocking_card_id NOT IN ? AND blocking_card_id NOT IN (?)", cardIDs, cardIDs, onBoard).
+		Delete(&models.CardDependency{}).Error
+}
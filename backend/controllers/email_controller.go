@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"bytes"
+	"net/http"
+	"trello-backend/notifications"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ShowEmailPreferenceChange asks to confirm the change in an email preference
+// link. Nothing changes on GET, since mail scanners follow links.
+func ShowEmailPreferenceChange(c *gin.Context) {
+	preferencePage(c, false)
+}
+
+// ApplyEmailPreferenceChange makes the change in an email preference link. Mail
+// clients also POST here for one-click unsubscribe (RFC 8058).
+func ApplyEmailPreferenceChange(c *gin.Context) {
+	preferencePage(c, true)
+}
+
+func preferencePage(c *gin.Context, apply bool) {
+	change, err := notifications.ParsePreferenceToken(c.Query("token"))
+	if err != nil {
+		c.String(http.StatusBadRequest, "This link is not valid.")
+		return
+	}
+
+	if apply {
+		if err := change.Apply(); err != nil {
+			c.String(http.StatusInternalServerError, "Failed to update your email settings.")
+			return
+		}
+	}
+
+	var page bytes.Buffer
+	if err := notifications.RenderPreferencePage(&page, change, apply); err != nil {
+		c.String(http.StatusInternalServerError, "Failed to show this page.")
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", page.Bytes())
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval is how often an idle stream sends a comment, so proxies
+// don't close it and removed members are noticed
+const heartbeatInterval = 15 * time.Second
+
+// StreamBoardEvents streams a board's list and card changes as Server-Sent
+// Events. Clients reconnecting with Last-Event-ID get the events they missed.
+func StreamBoardEvents(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("lastEventId")
+	}
+	var after int64
+	if lastEventID != "" {
+		var err error
+		if after, err = strconv.ParseInt(lastEventID, 10, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	stream, cancel := events.Default().Subscribe(board.ID, after)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	userID, _ := c.Get("userID")
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				return false
+			}
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(event.ID, 10), Event: event.Type, Data: event})
+			return true
+		case <-heartbeat.C:
+			// Stop streaming to users who have been removed from the board
+			var member models.Board
+			if err := config.DB.Preload("Members").Where("id = ?", board.ID).First(&member).Error; err != nil ||
+				!isBoardMember(member, userID.(string)) {
+				return false
+			}
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// publishEvent sends a committed change made by the current user to the
+// board's live streams. Failures are logged; the change itself has succeeded.
+func publishEvent(c *gin.Context, boardID string, eventType string, data interface{}) {
+	userID, _ := c.Get("userID")
+	event, err := events.New(boardID, eventType, userID.(string), data)
+	if err == nil {
+		err = events.Publish(event)
+	}
+	if err != nil {
+		log.Printf("Failed to publish %s event: %v", eventType, err)
+	}
+}
+
+// cardEventType tells a card move apart from other updates
+func cardEventType(before, after models.Card) string {
+	if before.ListID != after.ListID || before.Rank != after.Rank {
+		return events.CardMoved
+	}
+	return events.CardUpdated
+}
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/testdb"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fixture is a board owned by alice, with bob as a member, and a "To do" list
+// holding one card
+type fixture struct {
+	alice, bob models.User
+	board      models.Board
+	list       models.List
+	card       models.Card
+}
+
+func openFixture(t *testing.T) fixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	testdb.Open(t,
+		&models.User{}, &models.Board{}, &models.List{}, &models.Card{}, &models.Label{},
+		&models.Comment{}, &models.Mention{}, &models.Attachment{}, &models.Subscription{}, &models.Activity{},
+		&models.AutomationRule{}, &models.AutomationRun{},
+	)
+
+	f := fixture{
+		alice: models.User{ID: "alice", Username: "alice", Email: "alice@example.com", Password: "x"},
+		bob:   models.User{ID: "bob", Username: "bob", Email: "bob@example.com", Password: "x"},
+	}
+	f.board = models.Board{ID: "board", Name: "Launch", OwnerID: f.alice.ID, Members: []models.User{f.bob}}
+	f.list = models.List{ID: "todo", Name: "To do", Rank: "m", BoardID: f.board.ID}
+	f.card = models.Card{ID: "card", Title: "Write the announcement", Rank: "m", ListID: f.list.ID}
+	for _, record := range []interface{}{&f.alice, &f.bob, &f.board, &f.list, &f.card} {
+		if err := config.DB.Omit("Owner", "Board", "List").Create(record).Error; err != nil {
+			t.Fatalf("Failed to create %T: %v", record, err)
+		}
+	}
+	return f
+}
+
+// testContext returns a context for calling a handler as userID
+func testContext(userID string) (*gin.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	if userID != "" {
+		c.Set("userID", userID)
+	}
+	return c, recorder
+}
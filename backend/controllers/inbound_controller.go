@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/inbound"
+	"trello-backend/mentions"
+	"trello-backend/models"
+	"trello-backend/rank"
+	"trello-backend/subscriptions"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxInboundEmailSize is the largest email accepted, attachments included
+const maxInboundEmailSize = 25 << 20
+
+var errNoOpenList = errors.New("board has no open list")
+
+// ReceiveInboundEmail turns an email into a card or comment. Mail providers POST
+// the raw MIME message, either as the body or in an "email" or "body-mime" form field.
+// Mail to a board address becomes a card in its first list; replies to a
+// notification become comments on its card.
+func ReceiveInboundEmail(c *gin.Context) {
+	secret := os.Getenv("INBOUND_EMAIL_SECRET")
+	if secret == "" || inbound.Domain() == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inbound email is not enabled"})
+		return
+	}
+	// Only a header: query strings end up in access logs
+	if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Inbound-Secret")), []byte(secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid secret"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxInboundEmailSize)
+	var raw io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") ||
+		c.ContentType() == "application/x-www-form-urlencoded" {
+		field := c.PostForm("email")
+		if field == "" {
+			field = c.PostForm("body-mime")
+		}
+		raw = strings.NewReader(field)
+	}
+
+	msg, err := inbound.Parse(raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email"})
+		return
+	}
+
+	var kind, id string
+	found := false
+	for _, recipient := range msg.Recipients {
+		if kind, id, found = inbound.ParseAddress(recipient); found {
+			break
+		}
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown recipient"})
+		return
+	}
+
+	var sender models.User
+	if err := config.DB.Where("LOWER(email) = ?", msg.From).First(&sender).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unknown sender"})
+		return
+	}
+	// The card or comment is made as the sender
+	c.Set("userID", sender.ID)
+
+	if kind == inbound.KindBoard {
+		createCardFromEmail(c, id, msg)
+	} else {
+		createCommentFromEmail(c, id, msg)
+	}
+}
+
+// createCardFromEmail adds a card to the first open list of a board, titled
+// with the subject and described by the body
+func createCardFromEmail(c *gin.Context, boardID string, msg *inbound.Message) {
+	board, ok := findMemberBoard(c, boardID)
+	if !ok {
+		return
+	}
+	if board.ArchivedAt != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Board is archived"})
+		return
+	}
+
+	title := msg.Subject
+	if title == "" {
+		title = "(no subject)"
+	}
+	mentioned, err := memberMentions(config.DB, board, msg.Text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions"})
+		return
+	}
+
+	card := models.Card{ID: uuid.NewString(), Title: title, Description: msg.Text}
+	var list models.List
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("board_id = ? AND archived_at IS NULL", board.ID).Order("rank, id").
+			First(&list).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+			return errNoOpenList
+		} else if err != nil {
+			return err
+		}
+		if err := lockLists(tx, list.ID); err != nil {
+			return err
+		}
+		list.Board = board
+		if err := checkWIPLimit(tx, &card, list); err != nil {
+			return err
+		}
+
+		key, err := rank.At(tx, rank.CardsIn(list.ID), math.MaxInt32, "")
+		if err != nil {
+			return err
+		}
+		card.Rank = key
+		card.ListID = list.ID
+		if err := tx.Create(&card).Error; err != nil {
+			return err
+		}
+		if _, err := mentions.Sync(tx, card.ID, nil, mentioned); err != nil {
+			return err
+		}
+		userID, _ := c.Get("userID")
+		if err := saveAttachments(tx, card.ID, userID.(string), msg.Attachments); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionCreated,
+			EntityType: models.EntityCard, EntityID: card.ID, After: activity.Card(card),
+		})
+	})
+	if errors.Is(err, errNoOpenList) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Board has no open list"})
+		return
+	}
+	if errors.Is(err, errWIPLimitReached) {
+		c.JSON(http.StatusConflict, gin.H{"error": "List has reached its WIP limit"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create card"})
+		return
+	}
+
+	publishEvent(c, board.ID, events.CardCreated, card)
+	publishMentions(c, board.ID, card, "", mentioned)
+	c.JSON(http.StatusCreated, card)
+}
+
+// createCommentFromEmail adds the new text of a reply as a comment on the card
+func createCommentFromEmail(c *gin.Context, cardID string, msg *inbound.Message) {
+	body := inbound.StripReply(msg.Text)
+	if body == "" && len(msg.Attachments) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reply is empty"})
+		return
+	}
+	if body == "" {
+		body = "(attachment)"
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", cardID).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+	board, ok := findMemberBoard(c, card.List.BoardID)
+	if !ok {
+		return
+	}
+	mentioned, err := memberMentions(config.DB, board, body)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	comment := models.Comment{ID: uuid.NewString(), CardID: card.ID, AuthorID: userID.(string), Body: body}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Card", "Author").Create(&comment).Error; err != nil {
+			return err
+		}
+		if _, err := mentions.Sync(tx, card.ID, &comment.ID, mentioned); err != nil {
+			return err
+		}
+		if err := saveAttachments(tx, card.ID, comment.AuthorID, msg.Attachments); err != nil {
+			return err
+		}
+		return subscriptions.Watch(tx, comment.AuthorID, models.EntityCard, card.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+		return
+	}
+
+	config.DB.Where("id = ?", comment.AuthorID).First(&comment.Author)
+	publishEvent(c, board.ID, events.CommentCreated, gin.H{
+		"id": comment.ID, "cardId": card.ID, "listId": card.ListID, "cardTitle": card.Title, "body": comment.Body,
+		"mentioned": userIDs(mentioned),
+	})
+	publishMentions(c, board.ID, card, comment.ID, mentioned)
+	c.JSON(http.StatusCreated, newCommentResponse(comment, userSummaries(mentioned)))
+}
+
+// saveAttachments stores the files of an email on a card
+func saveAttachments(tx *gorm.DB, cardID, uploaderID string, files []inbound.Attachment) error {
+	for _, file := range files {
+		contentType := file.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		attachment := models.Attachment{
+			ID:          uuid.NewString(),
+			CardID:      cardID,
+			UploaderID:  uploaderID,
+			Filename:    file.Filename,
+			ContentType: contentType,
+			Size:        len(file.Data),
+			Data:        file.Data,
+		}
+		if err := tx.Omit("Card", "Uploader").Create(&attachment).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetBoardEmailAddress returns the address that turns emails into cards on the board
+func GetBoardEmailAddress(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+	address := inbound.BoardAddress(board.ID)
+	if address == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Inbound email is not enabled"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"address": address})
+}
+
+// GetCardAttachments lists the files on a card, without their contents
+func GetCardAttachments(c *gin.Context) {
+	card, ok := findMemberCard(c)
+	if !ok {
+		return
+	}
+
+	var attachments []models.Attachment
+	if err := config.DB.Omit("data").Preload("Uploader").Where("card_id = ?", card.ID).
+		Order("created_at, id").Find(&attachments).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get attachments"})
+		return
+	}
+
+	response := make([]models.AttachmentResponse, len(attachments))
+	for i, attachment := range attachments {
+		response[i] = models.AttachmentResponse{
+			ID:          attachment.ID,
+			CardID:      attachment.CardID,
+			Uploader:    models.UserSummary{ID: attachment.Uploader.ID, Username: attachment.Uploader.Username},
+			Filename:    attachment.Filename,
+			ContentType: attachment.ContentType,
+			Size:        attachment.Size,
+			CreatedAt:   attachment.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// DownloadCardAttachment sends the contents of a file on a card
+func DownloadCardAttachment(c *gin.Context) {
+	card, ok := findMemberCard(c)
+	if !ok {
+		return
+	}
+
+	var attachment models.Attachment
+	if err := config.DB.Where("id = ? AND card_id = ?", c.Param("attachmentId"), card.ID).
+		First(&attachment).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename}))
+	c.Data(http.StatusOK, attachment.ContentType, attachment.Data)
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"trello-backend/config"
+	"trello-backend/inbound"
+	"trello-backend/middlewares"
+	"trello-backend/models"
+)
+
+func enableInboundEmail(t *testing.T) {
+	t.Setenv("INBOUND_EMAIL_DOMAIN", "in.example.com")
+	t.Setenv("INBOUND_EMAIL_SECRET", "inbound secret")
+	previous := middlewares.JwtSecret
+	middlewares.JwtSecret = []byte("test secret")
+	t.Cleanup(func() { middlewares.JwtSecret = previous })
+}
+
+// receiveEmail posts a raw email to ReceiveInboundEmail with the given secret header
+func receiveEmail(secret, target, raw string) *httptest.ResponseRecorder {
+	c, recorder := testContext("")
+	c.Request = httptest.NewRequest(http.MethodPost, target, strings.NewReader(strings.ReplaceAll(raw, "\n", "\r\n")))
+	c.Request.Header.Set("Content-Type", "message/rfc822")
+	if secret != "" {
+		c.Request.Header.Set("X-Inbound-Secret", secret)
+	}
+	ReceiveInboundEmail(c)
+	return recorder
+}
+
+func TestInboundEmailNeedsSecretHeader(t *testing.T) {
+	enableInboundEmail(t)
+	f := openFixture(t)
+	raw := "From: alice@example.com\nTo: " + inbound.BoardAddress(f.board.ID) + "\nSubject: Hi\n\nbody\n"
+
+	if code := receiveEmail("wrong", "/inbound/email", raw).Code; code != http.StatusUnauthorized {
+		t.Errorf("wrong secret: status %d, want 401", code)
+	}
+	if code := receiveEmail("", "/inbound/email?secret=inbound+secret", raw).Code; code != http.StatusUnauthorized {
+		t.Errorf("secret in query: status %d, want 401", code)
+	}
+}
+
+func TestInboundEmailRejectsUnknownSender(t *testing.T) {
+	enableInboundEmail(t)
+	f := openFixture(t)
+
+	recorder := receiveEmail("inbound secret", "/inbound/email",
+		"From: mallory@example.com\nTo: "+inbound.BoardAddress(f.board.ID)+"\nSubject: Free money\n\nClick here\n")
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want 403: %s", recorder.Code, recorder.Body)
+	}
+
+	var cards int64
+	config.DB.Model(&models.Card{}).Count(&cards)
+	if cards != 1 {
+		t.Fatalf("%d cards, want only the fixture's", cards)
+	}
+}
+
+func TestInboundEmailRejectsSenderOffBoard(t *testing.T) {
+	enableInboundEmail(t)
+	f := openFixture(t)
+	carol := models.User{ID: "carol", Username: "carol", Email: "carol@example.com", Password: "x"}
+	config.DB.Create(&carol)
+
+	recorder := receiveEmail("inbound secret", "/inbound/email",
+		"From: Carol <CAROL@example.com>\nTo: "+inbound.ReplyAddress(f.card.ID)+"\nSubject: Re: card\n\nMe too\n")
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("status %d, want 403: %s", recorder.Code, recorder.Body)
+	}
+}
+
+func TestInboundReplyBecomesComment(t *testing.T) {
+	enableInboundEmail(t)
+	f := openFixture(t)
+
+	recorder := receiveEmail("inbound secret", "/inbound/email", `From: Bob <Bob@Example.com>
+To: Trello <`+inbound.ReplyAddress(f.card.ID)+`>
+Subject: Re: Write the announcement
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Draft is attached, @alice can you review?
+
+`+inbound.ReplyMarker+`
+alice commented on =E2=80=9CWrite the announcement=E2=80=9D
+--mixed
+Content-Type: text/plain; name="draft.txt"
+Content-Disposition: attachment; filename="draft.txt"
+
+We are launching.
+--mixed--
+`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status %d, want 201: %s", recorder.Code, recorder.Body)
+	}
+
+	var comment models.Comment
+	if err := config.DB.Where("card_id = ?", f.card.ID).First(&comment).Error; err != nil {
+		t.Fatalf("no comment was created: %v", err)
+	}
+	if comment.AuthorID != f.bob.ID || comment.Body != "Draft is attached, @alice can you review?" {
+		t.Fatalf("comment by %s: %q", comment.AuthorID, comment.Body)
+	}
+
+	var attachment models.Attachment
+	if err := config.DB.Where("card_id = ?", f.card.ID).First(&attachment).Error; err != nil {
+		t.Fatalf("no attachment was saved: %v", err)
+	}
+	if attachment.Filename != "draft.txt" || attachment.UploaderID != f.bob.ID || string(attachment.Data) != "We are launching." {
+		t.Fatalf("attachment %q from %s: %q", attachment.Filename, attachment.UploaderID, attachment.Data)
+	}
+
+	var mentioned []string
+	config.DB.Model(&models.Mention{}).Where("comment_id = ?", comment.ID).Pluck("user_id", &mentioned)
+	if len(mentioned) != 1 || mentioned[0] != f.alice.ID {
+		t.Fatalf("mentioned %v, want alice", mentioned)
+	}
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"net/http"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetBoardLabels returns the labels defined on a board
+func GetBoardLabels(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var labels []models.Label
+	if err := config.DB.Where("board_id = ?", board.ID).Order("name").Find(&labels).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get labels"})
+		return
+	}
+
+	response := make([]models.LabelResponse, len(labels))
+	for i, label := range labels {
+		response[i] = newLabelResponse(label)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateLabel adds a label to a board
+func CreateLabel(c *gin.Context) {
+	var input struct {
+		Name  string `json:"name" binding:"required"`
+		Color string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	label := models.Label{
+		ID:      uuid.NewString(),
+		BoardID: board.ID,
+		Name:    input.Name,
+		Color:   input.Color,
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&label).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionCreated,
+			EntityType: models.EntityLabel, EntityID: label.ID, After: activity.Label(label),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create label"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, newLabelResponse(label))
+}
+
+// UpdateLabel renames or recolours a label
+func UpdateLabel(c *gin.Context) {
+	var input struct {
+		Name  string  `json:"name"`
+		Color *string `json:"color"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var label models.Label
+	if err := config.DB.Where("id = ? AND board_id = ?", c.Param("labelId"), board.ID).First(&label).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	before := activity.Label(label)
+	if input.Name != "" {
+		label.Name = input.Name
+	}
+	if input.Color != nil {
+		label.Color = *input.Color
+	}
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&label).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionUpdated,
+			EntityType: models.EntityLabel, EntityID: label.ID, Before: before, After: activity.Label(label),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLabelResponse(label))
+}
+
+// DeleteLabel removes a label from a board and from all of its cards
+func DeleteLabel(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var label models.Label
+	if err := config.DB.Where("id = ? AND board_id = ?", c.Param("labelId"), board.ID).First(&label).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Label not found"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&label).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionDeleted,
+			EntityType: models.EntityLabel, EntityID: label.ID, Before: activity.Label(label),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label deleted successfully"})
+}
+
+// AddCardLabel attaches one of the board's labels to a card
+func AddCardLabel(c *gin.Context) {
+	var input struct {
+		LabelID string `json:"labelId" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, card.List.BoardID)
+	if !ok {
+		return
+	}
+
+	// Labels can only be used on the board that defines them
+	var label models.Label
+	if err := config.DB.Where("id = ? AND board_id = ?", input.LabelID, board.ID).First(&label).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Label does not belong to this board"})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		labelled, err := linkedToCard(tx, "card_labels", "label_id", card.ID, label.ID)
+		if err != nil || labelled {
+			return err
+		}
+		if err := tx.Model(&card).Association("Labels").Append(&label); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionLabelAdded,
+			EntityType: models.EntityCard, EntityID: card.ID, After: activity.Label(label),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add label"})
+		return
+	}
+
+	publishEvent(c, board.ID, events.LabelAdded, gin.H{
+		"cardId": card.ID, "listId": card.ListID, "title": card.Title, "labelId": label.ID,
+	})
+	c.JSON(http.StatusOK, gin.H{"message": "Label added successfully"})
+}
+
+// RemoveCardLabel detaches a label from a card
+func RemoveCardLabel(c *gin.Context) {
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		labelled, err := linkedToCard(tx, "card_labels", "label_id", card.ID, c.Param("labelId"))
+		if err != nil || !labelled {
+			return err
+		}
+		var label models.Label
+		if err := tx.Where("id = ?", c.Param("labelId")).First(&label).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&card).Association("Labels").Delete(&label); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: card.List.BoardID, Action: activity.ActionLabelRemoved,
+			EntityType: models.EntityCard, EntityID: card.ID, Before: activity.Label(label),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove label"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Label removed successfully"})
+}
+
+func newLabelResponse(label models.Label) models.LabelResponse {
+	return models.LabelResponse{
+		ID:    label.ID,
+		Name:  label.Name,
+		Color: label.Color,
+	}
+}
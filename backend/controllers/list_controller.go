@@ -1,12 +1,22 @@
 package controllers
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"slices"
+	"time"
+	"trello-backend/activity"
 	"trello-backend/config"
+	"trello-backend/events"
 	"trello-backend/models"
+	"trello-backend/rank"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateList creates a new list in a board
@@ -16,12 +26,18 @@ func CreateList(c *gin.Context) {
 	// Validate input
 	var input struct {
 		Name     string `json:"name" binding:"required"`
-		Position int    `json:"position"`
+		Position *int   `json:"position"` // index among the board's lists, appended when omitted
+		IsDone   bool   `json:"isDone"`
+		WIPLimit *int   `json:"wipLimit"` // most cards the list should hold, unlimited when omitted
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	if err := validWIPLimit(input.WIPLimit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Check if board exists and user has access
 	var board models.Board
@@ -46,19 +62,44 @@ func CreateList(c *gin.Context) {
 		return
 	}
 
+	position := math.MaxInt32
+	if input.Position != nil {
+		position = *input.Position
+	}
+
 	// Create new list
 	list := models.List{
 		ID:       uuid.NewString(),
 		Name:     input.Name,
-		Position: input.Position,
 		BoardID:  boardID,
+		IsDone:   input.IsDone,
+		WIPLimit: input.WIPLimit,
 	}
 
-	if err := config.DB.Create(&list).Error; err != nil {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		// Lock the board so concurrent creates can't take the same rank
+		if err := lockBoard(tx, boardID); err != nil {
+			return err
+		}
+		key, err := rank.At(tx, rank.ListsIn(boardID), position, "")
+		if err != nil {
+			return err
+		}
+		list.Rank = key
+		if err := tx.Create(&list).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: list.BoardID, Action: activity.ActionCreated,
+			EntityType: models.EntityList, EntityID: list.ID, After: activity.List(list),
+		})
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create list"})
 		return
 	}
 
+	publishEvent(c, list.BoardID, events.ListCreated, list)
 	c.JSON(http.StatusCreated, list)
 }
 
@@ -81,7 +122,7 @@ func GetBoardLists(c *gin.Context) {
 
 	// Get all lists
 	var lists []models.List
-	if err := config.DB.Where("board_id = ?", boardID).Order("position").Find(&lists).Error; err != nil {
+	if err := config.DB.Where("board_id = ?", boardID).Order("rank, id").Find(&lists).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get lists"})
 		return
 	}
@@ -123,12 +164,26 @@ func UpdateBoardList(c *gin.Context) {
 	// Validate input
 	var input struct {
 		Name     string `json:"name" binding:"required"`
-		Position int    `json:"position"`
+		Position *int   `json:"position"` // the list stays where it is when omitted
+		IsDone   *bool  `json:"isDone"`
+		// Kept when omitted, like on PATCH; null removes the limit
+		WIPLimit json.RawMessage `json:"wipLimit"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
 		return
 	}
+	var wipLimit *int
+	if len(input.WIPLimit) > 0 {
+		if err := json.Unmarshal(input.WIPLimit, &wipLimit); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+		if err := validWIPLimit(wipLimit); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	// Get the list
 	var list models.List
@@ -150,15 +205,58 @@ func UpdateBoardList(c *gin.Context) {
 		return
 	}
 
-	// Update list
-	list.Name = input.Name
-	list.Position = input.Position
+	before := activity.List(list)
+	tx := config.DB.Begin()
+
+	// The board is locked before the list, in the order moveList takes them
+	if err := lockBoard(tx, list.BoardID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+		return
+	}
 
-	if err := config.DB.Save(&list).Error; err != nil {
+	// Update list
+	updates := map[string]interface{}{"name": input.Name}
+	if input.IsDone != nil {
+		updates["is_done"] = *input.IsDone
+	}
+	if len(input.WIPLimit) > 0 {
+		updates["wip_limit"] = wipLimit
+	}
+	if err := tx.Model(&list).Updates(updates).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
 		return
 	}
+	if input.Position != nil {
+		if _, err := moveList(tx, list, *input.Position); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errPositionOutOfRange) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list position"})
+			return
+		}
+	}
+
+	if err := logListChange(c, tx, activity.ActionUpdated, list.BoardID, list.ID, before); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
 
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	if err := config.DB.Where("id = ?", list.ID).First(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get list"})
+		return
+	}
+
+	publishEvent(c, list.BoardID, events.ListUpdated, list)
 	c.JSON(http.StatusOK, list)
 }
 
@@ -196,11 +294,169 @@ func DeleteBoardList(c *gin.Context) {
 		return
 	}
 
-	// Delete list
-	if err := config.DB.Delete(&list).Error; err != nil {
+	// Move the list and its cards to the trash together, so restoring the list brings them back
+	deletedAt := time.Now().UTC().Truncate(time.Microsecond)
+	tx := config.DB.Begin()
+
+	if err := tx.Model(&models.Card{}).Where("list_id = ?", list.ID).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cards"})
+		return
+	}
+	if err := tx.Model(&list).Update("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete list"})
 		return
 	}
+	if err := logActivity(c, tx, activity.Entry{
+		BoardID: list.BoardID, Action: activity.ActionDeleted,
+		EntityType: models.EntityList, EntityID: list.ID, Before: activity.List(list),
+	}); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
 
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	publishEvent(c, list.BoardID, events.ListDeleted, gin.H{"id": list.ID, "name": list.Name})
 	c.JSON(http.StatusOK, gin.H{"message": "List deleted successfully"})
 }
+
+var errPositionOutOfRange = errors.New("position is out of range")
+
+// MoveBoardList moves a list to a new index on its board and renormalizes the order of all lists
+func MoveBoardList(c *gin.Context) {
+	boardID := c.Param("boardId")
+	listID := c.Param("listId")
+
+	var input struct {
+		Position *int   `json:"position" binding:"required"`
+		BoardID  string `json:"boardId"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, boardID)
+	if !ok {
+		return
+	}
+
+	// A boardId moves the list to another board the user can also access
+	targetBoardID := board.ID
+	if input.BoardID != "" {
+		target, ok := findMemberBoard(c, input.BoardID)
+		if !ok {
+			return
+		}
+		targetBoardID = target.ID
+	}
+
+	var list models.List
+	if err := config.DB.Where("id = ? AND board_id = ?", listID, board.ID).First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	tx := config.DB.Begin()
+	var lists []models.List
+	var err error
+	if targetBoardID != board.ID {
+		lists, err = moveListToBoard(tx, list, targetBoardID, *input.Position)
+	} else {
+		lists, err = moveList(tx, list, *input.Position)
+	}
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, errPositionOutOfRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move list"})
+		return
+	}
+
+	if err := logListChange(c, tx, activity.ActionMoved, board.ID, list.ID, activity.List(list)); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	// A list leaving the board is a move for the clients of both boards
+	for _, id := range slices.Compact([]string{board.ID, targetBoardID}) {
+		publishEvent(c, id, events.ListMoved, gin.H{"id": list.ID, "name": list.Name, "boardId": targetBoardID, "lists": lists})
+	}
+	list.BoardID = targetBoardID
+	c.JSON(http.StatusOK, lists)
+}
+
+// lockBoard locks a board until tx ends. Handlers that change a list lock its
+// board first, as moveList does, so a rename can't deadlock with a move.
+func lockBoard(tx *gorm.DB, boardID string) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", boardID).First(&models.Board{}).Error
+}
+
+// moveList puts list at position among the board's visible lists and respreads the
+// keys of every list on the board. The board and its lists stay locked until tx ends,
+// so concurrent moves are applied one after another. It returns the lists in their new order.
+func moveList(tx *gorm.DB, list models.List, position int) ([]models.List, error) {
+	if err := lockBoard(tx, list.BoardID); err != nil {
+		return nil, err
+	}
+
+	var lists []models.List
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("board_id = ?", list.BoardID).Order("rank, id").Find(&lists).Error; err != nil {
+		return nil, err
+	}
+
+	// Positions count visible lists only; archived lists keep their slots
+	var visible []models.List
+	var moved *models.List
+	for i := range lists {
+		if lists[i].ID == list.ID {
+			moved = &lists[i]
+		} else if lists[i].ArchivedAt == nil {
+			visible = append(visible, lists[i])
+		}
+	}
+	if moved == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if position < 0 || position > len(visible) {
+		return nil, errPositionOutOfRange
+	}
+	visible = append(visible[:position], append([]models.List{*moved}, visible[position:]...)...)
+
+	ordered := make([]models.List, 0, len(lists))
+	next := 0
+	for _, l := range lists {
+		switch {
+		case l.ID == list.ID:
+			continue
+		case l.ArchivedAt != nil:
+			ordered = append(ordered, l)
+		default:
+			ordered = append(ordered, visible[next])
+			next++
+		}
+	}
+	ordered = append(ordered, visible[next:]...)
+
+	for i, key := range rank.Spread(len(ordered)) {
+		if err := tx.Model(&ordered[i]).Update("rank", key).Error; err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
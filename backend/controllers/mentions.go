@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/mentions"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// mentionedMembers returns the users mentioned in text, who must all be members
+// of the board. Otherwise the board owner, who can add members, gets the others
+// back under "invite" so the client can offer to add them and try again; other
+// members can't invite anyone and get an error. It writes the error response
+// and returns false when text mentions outsiders.
+func mentionedMembers(c *gin.Context, boardID string, text string) ([]models.User, bool) {
+	users, err := mentions.Users(config.DB, text)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve mentions"})
+		return nil, false
+	}
+	if len(users) == 0 {
+		return nil, true
+	}
+
+	var board models.Board
+	if err := config.DB.Preload("Members").Where("id = ?", boardID).First(&board).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Board not found"})
+		return nil, false
+	}
+
+	var outsiders []models.UserSummary
+	var names []string
+	for _, user := range users {
+		if !isBoardMember(board, user.ID) {
+			outsiders = append(outsiders, models.UserSummary{ID: user.ID, Username: user.Username})
+			names = append(names, user.Username)
+		}
+	}
+	if len(outsiders) == 0 {
+		return users, true
+	}
+
+	userID, _ := c.Get("userID")
+	if board.OwnerID == userID.(string) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Mentioned users are not members of this board", "invite": outsiders})
+		return nil, false
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "Mentioned users are not members of this board: " + strings.Join(names, ", ")})
+	return nil, false
+}
+
+// memberMentions resolves the @mentions in text, dropping anyone who isn't on
+// the board. Unlike the API, email and automation rules have no one to ask
+// before inviting them.
+func memberMentions(tx *gorm.DB, board models.Board, text string) ([]models.User, error) {
+	users, err := mentions.Users(tx, text)
+	if err != nil {
+		return nil, err
+	}
+	var members []models.User
+	for _, user := range users {
+		if isBoardMember(board, user.ID) {
+			members = append(members, user)
+		}
+	}
+	return members, nil
+}
+
+// publishMentions tells the users newly mentioned on a card, in its description
+// or in the given comment, that they were mentioned
+func publishMentions(c *gin.Context, boardID string, card models.Card, commentID string, users []models.User) {
+	if len(users) == 0 {
+		return
+	}
+	publishEvent(c, boardID, events.MentionCreated, gin.H{
+		"cardId": card.ID, "listId": card.ListID, "cardTitle": card.Title, "commentId": commentID, "mentioned": userIDs(users),
+	})
+}
+
+func userIDs(users []models.User) []string {
+	ids := make([]string, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func userSummaries(users []models.User) []models.UserSummary {
+	summaries := make([]models.UserSummary, len(users))
+	for i, user := range users {
+		summaries[i] = models.UserSummary{ID: user.ID, Username: user.Username}
+	}
+	return summaries
+}
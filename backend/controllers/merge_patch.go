@@ -0,0 +1,81 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// mergePatch is a JSON Merge Patch (RFC 7396) document. A missing key leaves the
+// field untouched, while an explicit null clears it.
+type mergePatch map[string]json.RawMessage
+
+// bindMergePatch reads the request body as a merge patch and rejects keys
+// outside allowed, so typos don't silently do nothing
+func bindMergePatch(c *gin.Context, allowed ...string) (mergePatch, error) {
+	var patch mergePatch
+	if err := json.NewDecoder(c.Request.Body).Decode(&patch); err != nil || patch == nil {
+		return nil, errors.New("invalid input")
+	}
+
+	for key := range patch {
+		known := false
+		for _, field := range allowed {
+			if key == field {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, errors.New("unknown field: " + key)
+		}
+	}
+	return patch, nil
+}
+
+func (p mergePatch) has(key string) bool {
+	_, ok := p[key]
+	return ok
+}
+
+func (p mergePatch) isNull(key string) bool {
+	raw, ok := p[key]
+	return ok && bytes.Equal(bytes.TrimSpace(raw), []byte("null"))
+}
+
+// decode unmarshals a present, non-null field into v
+func (p mergePatch) decode(key string, v interface{}) error {
+	if err := json.Unmarshal(p[key], v); err != nil {
+		return errors.New("invalid value for " + key)
+	}
+	return nil
+}
+
+// requiredString decodes a field that can be changed but not cleared
+func (p mergePatch) requiredString(key string) (string, error) {
+	var value string
+	if p.isNull(key) {
+		return "", errors.New(key + " cannot be null")
+	}
+	if err := p.decode(key, &value); err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", errors.New(key + " cannot be empty")
+	}
+	return value, nil
+}
+
+// requiredInt decodes a number field that can be changed but not cleared
+func (p mergePatch) requiredInt(key string) (int, error) {
+	var value int
+	if p.isNull(key) {
+		return 0, errors.New(key + " cannot be null")
+	}
+	if err := p.decode(key, &value); err != nil {
+		return 0, err
+	}
+	return value, nil
+}
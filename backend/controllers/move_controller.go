@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MoveCardToBoard moves a card to a list on any board the user can access.
+// Labels and assignees that don't exist on the target board are remapped or dropped.
+func MoveCardToBoard(c *gin.Context) {
+	var input struct {
+		BoardID  string `json:"boardId"`
+		ListID   string `json:"listId" binding:"required"`
+		Position *int   `json:"position"`
+		Version  *int   `json:"version"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	expectedVersion, err := requestedVersion(c, input.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+	sourceBoardID := card.List.BoardID
+
+	// The user needs access to both the source and the target board
+	if _, ok := findMemberBoard(c, sourceBoardID); !ok {
+		return
+	}
+	targetBoardID := sourceBoardID
+	if input.BoardID != "" {
+		targetBoardID = input.BoardID
+	}
+	if _, ok := findMemberBoard(c, targetBoardID); !ok {
+		return
+	}
+
+	var target models.List
+	if err := config.DB.Where("id = ? AND board_id = ?", input.ListID, targetBoardID).First(&target).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target list not found"})
+		return
+	}
+
+	// Without a position the card goes to the end of the target list
+	position := math.MaxInt32
+	if input.Position != nil {
+		position = *input.Position
+	}
+
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Printf("MoveCardToBoard panic: %v", r)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move card"})
+		}
+	}()
+
+	card, err = lockCard(tx, c.Param("cardId"), target.ID)
+	if errors.Is(err, errCardMoved) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Card was moved by someone else, try again"})
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if expectedVersion != nil && *expectedVersion != card.Version {
+		tx.Rollback()
+		c.Header("ETag", cardETag(card))
+		c.JSON(http.StatusConflict, gin.H{"error": "Card was changed by someone else", "card": card})
+		return
+	}
+	before := card
+
+	// Remap first, so blockers left on the source board don't count against the move
+	if targetBoardID != sourceBoardID {
+		if err := remapCardsToBoard(tx, targetBoardID, []string{card.ID}); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move card"})
+			return
+		}
+	}
+
+	if err := moveCard(tx, &card, target.ID, position); err != nil {
+		tx.Rollback()
+		if errors.Is(err, errCardBlocked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Card is blocked by cards that are not done"})
+			return
+		}
+		if errors.Is(err, errWIPLimitReached) {
+			c.JSON(http.StatusConflict, gin.H{"error": "List has reached its WIP limit"})
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Target list not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move card"})
+		return
+	}
+
+	card.Version++
+	if err := tx.Save(&card).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move card"})
+		return
+	}
+	if err := logCardChange(c, tx, sourceBoardID, targetBoardID, before, card); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+	for _, id := range slices.Compact([]string{sourceBoardID, targetBoardID}) {
+		publishEvent(c, id, events.CardMoved, card)
+	}
+	c.Header("ETag", cardETag(card))
+	c.JSON(http.StatusOK, card)
+}
+
+// moveListToBoard hands a list and all of its cards over to another board and
+// puts it at position among the target board's lists
+func moveListToBoard(tx *gorm.DB, list models.List, targetBoardID string, position int) ([]models.List, error) {
+	// Lock both boards in ID order so opposite moves can't deadlock
+	boardIDs := []string{list.BoardID, targetBoardID}
+	slices.Sort(boardIDs)
+	var boards []models.Board
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", boardIDs).Order("id").Find(&boards).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&list).Update("board_id", targetBoardID).Error; err != nil {
+		return nil, err
+	}
+	list.BoardID = targetBoardID
+
+	// Trashed cards move too, so they can still be restored into the list
+	var cardIDs []string
+	if err := tx.Unscoped().Model(&models.Card{}).Where("list_id = ?", list.ID).Pluck("id", &cardIDs).Error; err != nil {
+		return nil, err
+	}
+	if err := remapCardsToBoard(tx, targetBoardID, cardIDs); err != nil {
+		return nil, err
+	}
+
+	return moveList(tx, list, position)
+}
+
+// remapCardsToBoard fixes up the labels, custom field values and assignees of
+// cards that moved to another board. Each label is swapped for the target board's
+// label of the same name, or dropped if there is none; field values are handled
+// by remapFieldValues. Dependencies on cards left behind are dropped, and
+// assignees who can't access the target board are unassigned.
+func remapCardsToBoard(tx *gorm.DB, boardID string, cardIDs []string) error {
+	if len(cardIDs) == 0 {
+		return nil
+	}
+
+	var links []struct {
+		CardID string
+		Name   string
+	}
+	if err := tx.Table("card_labels").
+		Select("card_labels.card_id, labels.name").
+		Joins("JOIN labels ON labels.id = card_labels.label_id").
+		Where("card_labels.card_id IN ?", cardIDs).
+		Scan(&links).Error; err != nil {
+		return err
+	}
+
+	var labels []models.Label
+	if err := tx.Where("board_id = ?", boardID).Find(&labels).Error; err != nil {
+		return err
+	}
+	labelByName := make(map[string]string, len(labels))
+	for _, label := range labels {
+		labelByName[strings.ToLower(label.Name)] = label.ID
+	}
+
+	if err := tx.Where("card_id IN ?", cardIDs).Delete(&models.CardLabel{}).Error; err != nil {
+		return err
+	}
+	var remapped []models.CardLabel
+	for _, link := range links {
+		if labelID, ok := labelByName[strings.ToLower(link.Name)]; ok {
+			remapped = append(remapped, models.CardLabel{CardID: link.CardID, LabelID: labelID})
+		}
+	}
+	if len(remapped) > 0 {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&remapped).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := remapFieldValues(tx, boardID, cardIDs); err != nil {
+		return err
+	}
+	if err := dropCrossBoardDependencies(tx, boardID, cardIDs); err != nil {
+		return err
+	}
+	return dropNonMemberAssignees(tx, boardID, cardIDs)
+}
+
+// dropNonMemberAssignees unassigns users who can't access boardID from the given cards
+func dropNonMemberAssignees(tx *gorm.DB, boardID string, cardIDs []string) error {
+	if len(cardIDs) == 0 {
+		return nil
+	}
+	return tx.Exec(`DELETE FROM card_assignees
+		WHERE card_id IN ?
+		AND user_id NOT IN (SELECT user_id FROM board_members WHERE board_id = ?)
+		AND user_id <> (SELECT owner_id FROM boards WHERE id = ?)`, cardIDs, boardID, boardID).Error
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"net/http"
+	"slices"
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/notifications"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetNotifications returns a page of the current user's notifications, newest
+// first. "unread=true" leaves out the ones already read.
+func GetNotifications(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+
+	if before := c.Query("before"); before != "" {
+		var cursor models.Notification
+		if err := config.DB.Where("id = ? AND user_id = ?", before, userID).First(&cursor).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	// One extra row tells whether there is another page
+	var list []models.Notification
+	if err := query.Order("created_at DESC, id DESC").Limit(limit + 1).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+
+	page := models.NotificationPage{Notifications: []models.NotificationResponse{}}
+	if len(list) > limit {
+		list = list[:limit]
+		page.NextCursor = list[limit-1].ID
+	}
+	for _, notification := range list {
+		page.Notifications = append(page.Notifications, models.NotificationResponse{
+			ID:        notification.ID,
+			Type:      notification.Type,
+			Title:     notification.Title,
+			Body:      notification.Body,
+			BoardID:   notification.BoardID,
+			CardID:    notification.CardID,
+			Read:      notification.ReadAt != nil,
+			ReadAt:    notification.ReadAt,
+			CreatedAt: notification.CreatedAt,
+		})
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetUnreadNotificationCount returns how many of the current user's notifications are unread
+func GetUnreadNotificationCount(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var count int64
+	if err := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"count": count})
+}
+
+// MarkNotificationRead marks one of the current user's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var notification models.Notification
+	if err := config.DB.Where("id = ? AND user_id = ?", c.Param("notificationId"), userID).
+		First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	// Reading it again keeps the original time
+	if notification.ReadAt == nil {
+		if err := config.DB.Model(&notification).Update("read_at", time.Now().UTC()).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the current user as read
+func MarkAllNotificationsRead(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	result := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now().UTC())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated": result.RowsAffected})
+}
+
+// GetNotificationPreferences returns the current user's channels for every notification type
+func GetNotificationPreferences(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var preferences []models.NotificationPreference
+	if err := config.DB.Where("user_id = ?", userID).Find(&preferences).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferenceResponse(preferences))
+}
+
+// UpdateNotificationPreferences sets the channels of the given notification
+// types. Types left out of the request keep their current setting.
+func UpdateNotificationPreferences(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var input []struct {
+		Type  string `json:"type"`
+		InApp *bool  `json:"inApp"`
+		Email *bool  `json:"email"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	preferences := make([]models.NotificationPreference, len(input))
+	for i, item := range input {
+		if item.Type == "" || item.InApp == nil || item.Email == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+		if !slices.Contains(notifications.Types, item.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown notification type: " + item.Type})
+			return
+		}
+		preferences[i] = models.NotificationPreference{
+			UserID: userID.(string),
+			Type:   item.Type,
+			InApp:  *item.InApp,
+			Email:  *item.Email,
+		}
+	}
+
+	var saved []models.NotificationPreference
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if len(preferences) > 0 {
+			if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+				DoUpdates: clause.AssignmentColumns([]string{"in_app", "email"}),
+			}).Create(&preferences).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ?", userID).Find(&saved).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, preferenceResponse(saved))
+}
+
+// preferenceResponse lists every notification type, filling in the defaults
+// for types the user has not set
+func preferenceResponse(preferences []models.NotificationPreference) []models.NotificationPreferenceResponse {
+	response := make([]models.NotificationPreferenceResponse, len(notifications.Types))
+	for i, notificationType := range notifications.Types {
+		response[i] = models.NotificationPreferenceResponse{Type: notificationType, InApp: true, Email: true}
+		for _, preference := range preferences {
+			if preference.Type == notificationType {
+				response[i].InApp = preference.InApp
+				response[i].Email = preference.Email
+			}
+		}
+	}
+	return response
+}
+
+// GetEmailSettings returns when the current user's notification emails are sent
+func GetEmailSettings(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivery": user.EmailDelivery})
+}
+
+// UpdateEmailSettings sets when the current user's notification emails are sent:
+// right away, in a daily or weekly digest, or not at all
+func UpdateEmailSettings(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var input struct {
+		Delivery string `json:"delivery" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if !notifications.ValidEmailDelivery(input.Delivery) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "delivery must be off, immediate, daily or weekly"})
+		return
+	}
+
+	result := config.DB.Model(&models.User{}).Where("id = ?", userID).Update("email_delivery", input.Delivery)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update email settings"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivery": input.Delivery})
+}
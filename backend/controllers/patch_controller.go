@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"time"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/mentions"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PatchBoard applies a JSON Merge Patch to a board
+func PatchBoard(c *gin.Context) {
+	patch, err := bindMergePatch(c, "name", "blockedMovePolicy", "wipMode")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+	before := activity.Board(board)
+
+	if patch.has("name") {
+		if board.Name, err = patch.requiredString("name"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if patch.has("blockedMovePolicy") {
+		if board.BlockedMovePolicy, err = patch.requiredString("blockedMovePolicy"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if board.BlockedMovePolicy != models.BlockedMoveWarn && board.BlockedMovePolicy != models.BlockedMoveError {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "blockedMovePolicy must be warn or error"})
+			return
+		}
+	}
+
+	if patch.has("wipMode") {
+		if board.WIPMode, err = patch.requiredString("wipMode"); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if board.WIPMode != models.WIPModeSoft && board.WIPMode != models.WIPModeHard {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "wipMode must be soft or hard"})
+			return
+		}
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&board).Updates(map[string]interface{}{
+			"name":                board.Name,
+			"blocked_move_policy": board.BlockedMovePolicy,
+			"wip_mode":            board.WIPMode,
+		}).Error; err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionUpdated,
+			EntityType: models.EntityBoard, EntityID: board.ID, Before: before, After: activity.Board(board),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update board"})
+		return
+	}
+
+	publishEvent(c, board.ID, events.BoardUpdated, boardSummary(board))
+	c.JSON(http.StatusOK, board)
+}
+
+// PatchBoardList applies a JSON Merge Patch to a list
+func PatchBoardList(c *gin.Context) {
+	patch, err := bindMergePatch(c, "name", "position", "isDone", "wipLimit")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var list models.List
+	if err := config.DB.Where("id = ?", c.Param("listId")).First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, list.BoardID); !ok {
+		return
+	}
+	before := activity.List(list)
+
+	tx := config.DB.Begin()
+
+	// The board is locked before the list, in the order moveList takes them
+	if err := lockBoard(tx, list.BoardID); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+		return
+	}
+
+	if patch.has("name") {
+		name, err := patch.requiredString("name")
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Model(&list).Update("name", name).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+			return
+		}
+	}
+
+	if patch.has("isDone") {
+		var isDone bool
+		if err := patch.decode("isDone", &isDone); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Model(&list).Update("is_done", isDone).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+			return
+		}
+	}
+
+	if patch.has("wipLimit") {
+		// null removes the limit
+		var limit *int
+		if err := patch.decode("wipLimit", &limit); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := validWIPLimit(limit); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := tx.Model(&list).Update("wip_limit", limit).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list"})
+			return
+		}
+	}
+
+	if patch.has("position") {
+		position, err := patch.requiredInt("position")
+		if err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if _, err := moveList(tx, list, position); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errPositionOutOfRange) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update list position"})
+			return
+		}
+	}
+
+	if err := logListChange(c, tx, activity.ActionUpdated, list.BoardID, list.ID, before); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+
+	if err := config.DB.Where("id = ?", list.ID).First(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get list"})
+		return
+	}
+
+	publishEvent(c, list.BoardID, events.ListUpdated, list)
+	c.JSON(http.StatusOK, list)
+}
+
+// PatchCard applies a JSON Merge Patch to a card. Unlike UpdateCard, only the
+// fields present in the patch change, and null clears optional fields.
+func PatchCard(c *gin.Context) {
+	patch, err := bindMergePatch(c,
+		"title", "description", "startDate", "dueDate", "dueComplete", "timezone", "listId", "position", "version")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var bodyVersion *int
+	if patch.has("version") && !patch.isNull("version") {
+		bodyVersion = new(int)
+		if err := patch.decode("version", bodyVersion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	expectedVersion, err := requestedVersion(c, bodyVersion)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var timezone string
+	if patch.has("timezone") && !patch.isNull("timezone") {
+		if err := patch.decode("timezone", &timezone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+	boardID := card.List.BoardID
+
+	if _, ok := findMemberBoard(c, boardID); !ok {
+		return
+	}
+
+	var moveTo []string
+	if patch.has("listId") {
+		targetListID, err := patch.requiredString("listId")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		moveTo = append(moveTo, targetListID)
+	}
+
+	tx := config.DB.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+			log.Printf("PatchCard panic: %v", r)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
+		}
+	}()
+
+	// Cards can only be moved between lists of the same board here
+	for _, listID := range moveTo {
+		if err := tx.Where("id = ? AND board_id = ?", listID, boardID).First(&models.List{}).Error; err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusNotFound, gin.H{"error": "New list not found"})
+			return
+		}
+	}
+
+	// Reload the card under a row lock for the version check
+	card, err = lockCard(tx, c.Param("cardId"), moveTo...)
+	if errors.Is(err, errCardMoved) {
+		tx.Rollback()
+		c.JSON(http.StatusConflict, gin.H{"error": "Card was moved by someone else, try again"})
+		return
+	}
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if expectedVersion != nil && *expectedVersion != card.Version {
+		tx.Rollback()
+		c.Header("ETag", cardETag(card))
+		c.JSON(http.StatusConflict, gin.H{"error": "Card was changed by someone else", "card": card})
+		return
+	}
+	before := card
+
+	if err := applyCardPatch(&card, patch, timezone); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var mentioned []models.User
+	if patch.has("description") {
+		var ok bool
+		if mentioned, ok = mentionedMembers(c, boardID, card.Description); !ok {
+			tx.Rollback()
+			return
+		}
+	}
+
+	if patch.has("listId") || patch.has("position") {
+		targetListID := card.ListID
+		if len(moveTo) > 0 {
+			targetListID = moveTo[0]
+		}
+
+		// Moving to another list without a position appends the card
+		position := math.MaxInt32
+		if patch.has("position") {
+			if position, err = patch.requiredInt("position"); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		if err := moveCard(tx, &card, targetListID, position); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errCardBlocked) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Card is blocked by cards that are not done"})
+				return
+			}
+			if errors.Is(err, errWIPLimitReached) {
+				c.JSON(http.StatusConflict, gin.H{"error": "List has reached its WIP limit"})
+				return
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "New list not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card position"})
+			return
+		}
+	}
+
+	card.Version++
+	if err := tx.Save(&card).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
+		return
+	}
+	// Only people who weren't mentioned before are told about it
+	var newlyMentioned []models.User
+	if patch.has("description") {
+		if newlyMentioned, err = mentions.Sync(tx, card.ID, nil, mentioned); err != nil {
+			tx.Rollback()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save mentions"})
+			return
+		}
+	}
+	if err := logCardChange(c, tx, boardID, boardID, before, card); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record activity"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit changes"})
+		return
+	}
+	publishEvent(c, boardID, cardEventType(before, card), card)
+	publishMentions(c, boardID, card, "", newlyMentioned)
+	c.Header("ETag", cardETag(card))
+	c.JSON(http.StatusOK, card)
+}
+
+// applyCardPatch copies the content fields of a patch onto card and validates the result
+func applyCardPatch(card *models.Card, patch mergePatch, timezone string) error {
+	var err error
+	if patch.has("title") {
+		if card.Title, err = patch.requiredString("title"); err != nil {
+			return err
+		}
+	}
+
+	if patch.has("description") {
+		card.Description = ""
+		if !patch.isNull("description") {
+			if err := patch.decode("description", &card.Description); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, field := range []struct {
+		key    string
+		target **time.Time
+	}{
+		{"startDate", &card.StartDate},
+		{"dueDate", &card.DueDate},
+	} {
+		if !patch.has(field.key) {
+			continue
+		}
+		var value string
+		if !patch.isNull(field.key) {
+			if err := patch.decode(field.key, &value); err != nil {
+				return err
+			}
+		}
+		if *field.target, err = parseCardDate(value, timezone); err != nil {
+			return err
+		}
+	}
+
+	if patch.has("dueComplete") {
+		card.DueComplete = false
+		if !patch.isNull("dueComplete") {
+			if err := patch.decode("dueComplete", &card.DueComplete); err != nil {
+				return err
+			}
+		}
+	}
+
+	return validateCardDates(card.StartDate, card.DueDate)
+}
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+	"trello-backend/activity"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// placeholderPattern matches template placeholders such as {{date}} or {{ sprint }}
+var placeholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// placeholderFiller returns a function that replaces placeholders with their
+// values. {{date}} defaults to today's date; unknown placeholders are left as they are.
+func placeholderFiller(variables map[string]string) func(string) string {
+	values := map[string]string{"date": time.Now().UTC().Format("2006-01-02")}
+	for name, value := range variables {
+		values[name] = value
+	}
+
+	return func(text string) string {
+		return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+			name := placeholderPattern.FindStringSubmatch(match)[1]
+			if value, ok := values[name]; ok {
+				return value
+			}
+			return match
+		})
+	}
+}
+
+// visibleTemplates selects the board templates a user can use: the built-in ones
+// and those on boards they own or are a member of
+func visibleTemplates(userID string) *gorm.DB {
+	return config.DB.Where("is_template = ?", true).
+		Where("built_in = ? OR owner_id = ? OR id IN (?)", true, userID,
+			config.DB.Table("board_members").Select("board_id").Where("user_id = ?", userID))
+}
+
+// GetBoardTemplates lists the board templates available to the current user
+func GetBoardTemplates(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var boards []models.Board
+	if err := visibleTemplates(userID.(string)).Order("built_in DESC, name").Find(&boards).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get templates"})
+		return
+	}
+
+	templates := make([]gin.H, len(boards))
+	for i, board := range boards {
+		templates[i] = gin.H{
+			"id":      board.ID,
+			"name":    board.Name,
+			"builtIn": board.BuiltIn,
+		}
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+// SetBoardTemplate marks a board as a template, or turns it back into a normal board
+func SetBoardTemplate(c *gin.Context) {
+	var input struct {
+		IsTemplate *bool `json:"isTemplate" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	// Only the owner decides whether the board is shared as a template
+	userID, _ := c.Get("userID")
+	if board.OwnerID != userID.(string) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the board owner can change this"})
+		return
+	}
+
+	if err := config.DB.Model(&board).Update("is_template", *input.IsTemplate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update board"})
+		return
+	}
+
+	c.JSON(http.StatusOK, board)
+}
+
+// createBoardFromTemplate creates a board for the current user with the labels,
+// lists and cards of a template. Placeholders are filled in from variables.
+func createBoardFromTemplate(c *gin.Context, templateID string, name string, variables map[string]string) {
+	userID, _ := c.Get("userID")
+
+	var template models.Board
+	if err := visibleTemplates(userID.(string)).Where("id = ?", templateID).First(&template).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	fill := placeholderFiller(variables)
+	if name == "" {
+		name = fill(template.Name)
+	}
+	board := models.Board{
+		ID:      uuid.NewString(),
+		Name:    name,
+		OwnerID: userID.(string),
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&board).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.BoardMember{BoardID: board.ID, UserID: board.OwnerID}).Error; err != nil {
+			return err
+		}
+		if _, err := copyBoardInto(tx, template, board, copyOptions{}, fill); err != nil {
+			return err
+		}
+		return logActivity(c, tx, activity.Entry{
+			BoardID: board.ID, Action: activity.ActionCreated,
+			EntityType: models.EntityBoard, EntityID: board.ID, After: activity.Board(board),
+		})
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create board from template"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, board)
+}
+
+// GetBoardCardTemplates lists the template cards of a board
+func GetBoardCardTemplates(c *gin.Context) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var cards []models.Card
+	if err := config.DB.Where("is_template = ? AND list_id IN (?)", true,
+		config.DB.Model(&models.List{}).Select("id").Where("board_id = ?", board.ID)).
+		Order("title").Find(&cards).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cards)
+}
+
+// SetCardTemplate marks a card as a template, or turns it back into a normal card
+func SetCardTemplate(c *gin.Context) {
+	var input struct {
+		IsTemplate *bool `json:"isTemplate" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+
+	card.IsTemplate = *input.IsTemplate
+	if err := config.DB.Model(&card).Updates(map[string]interface{}{
+		"is_template": card.IsTemplate,
+		"version":     gorm.Expr("version + 1"),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update card"})
+		return
+	}
+	card.Version++
+
+	c.JSON(http.StatusOK, card)
+}
+
+// findTemplateCard loads a template card from a board the current user can access.
+// It writes the error response and returns false when there is none.
+func findTemplateCard(c *gin.Context, cardID string) (models.Card, bool) {
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ? AND is_template = ?", cardID, true).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return card, false
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return card, false
+	}
+	return card, true
+}
+
+// copyTemplateLabels gives a card created from a template the template's labels,
+// remapped by name when the card lives on another board
+func copyTemplateLabels(tx *gorm.DB, template models.Card, cardID string, boardID string) error {
+	var links []models.CardLabel
+	if err := tx.Where("card_id = ?", template.ID).Find(&links).Error; err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return nil
+	}
+
+	for i := range links {
+		links[i].CardID = cardID
+	}
+	if err := tx.Create(&links).Error; err != nil {
+		return err
+	}
+
+	if template.List.BoardID != boardID {
+		return remapCardsToBoard(tx, boardID, []string{cardID})
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"net/http"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetReminderSettings returns the current user's due-date reminder settings
+func GetReminderSettings(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leadMinutes": user.ReminderLeadMinutes})
+}
+
+// UpdateReminderSettings sets how long before a due date the current user is reminded
+func UpdateReminderSettings(c *gin.Context) {
+	userID, _ := c.Get("userID")
+
+	var input struct {
+		LeadMinutes *int `json:"leadMinutes" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if *input.LeadMinutes < 0 || *input.LeadMinutes > models.MaxReminderLeadMinutes {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "leadMinutes must be between 0 and 43200"})
+		return
+	}
+
+	result := config.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("reminder_lead_minutes", *input.LeadMinutes)
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reminder settings"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leadMinutes": *input.LeadMinutes})
+}
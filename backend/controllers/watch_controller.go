@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"net/http"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/subscriptions"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WatchBoard subscribes the current user to changes anywhere on a board
+func WatchBoard(c *gin.Context) {
+	setBoardWatched(c, true)
+}
+
+// UnwatchBoard stops the current user watching a board
+func UnwatchBoard(c *gin.Context) {
+	setBoardWatched(c, false)
+}
+
+func setBoardWatched(c *gin.Context, watch bool) {
+	board, ok := findMemberBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+	setWatched(c, watch, models.EntityBoard, board.ID)
+}
+
+// WatchList subscribes the current user to changes to a list and its cards
+func WatchList(c *gin.Context) {
+	setListWatched(c, true)
+}
+
+// UnwatchList stops the current user watching a list
+func UnwatchList(c *gin.Context) {
+	setListWatched(c, false)
+}
+
+func setListWatched(c *gin.Context, watch bool) {
+	var list models.List
+	if err := config.DB.Where("id = ?", c.Param("listId")).First(&list).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "List not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, list.BoardID); !ok {
+		return
+	}
+	setWatched(c, watch, models.EntityList, list.ID)
+}
+
+// WatchCard subscribes the current user to changes to a card
+func WatchCard(c *gin.Context) {
+	setCardWatched(c, true)
+}
+
+// UnwatchCard stops the current user watching a card
+func UnwatchCard(c *gin.Context) {
+	setCardWatched(c, false)
+}
+
+func setCardWatched(c *gin.Context, watch bool) {
+	var card models.Card
+	if err := config.DB.Preload("List").Where("id = ?", c.Param("cardId")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Card not found"})
+		return
+	}
+
+	if _, ok := findMemberBoard(c, card.List.BoardID); !ok {
+		return
+	}
+	setWatched(c, watch, models.EntityCard, card.ID)
+}
+
+func setWatched(c *gin.Context, watch bool, entityType string, entityID string) {
+	userID, _ := c.Get("userID")
+
+	var err error
+	if watch {
+		err = subscriptions.Watch(config.DB, userID.(string), entityType, entityID)
+	} else {
+		err = subscriptions.Unwatch(config.DB, userID.(string), entityType, entityID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update watch state"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"watching": watch})
+}
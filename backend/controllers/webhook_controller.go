@@ -0,0 +1,305 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+	"trello-backend/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// GetWebhooks lists a board's webhooks; only the owner may see them
+func GetWebhooks(c *gin.Context) {
+	board, ok := findOwnedBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var hooks []models.Webhook
+	if err := config.DB.Where("board_id = ?", board.ID).Order("created_at, id").Find(&hooks).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get webhooks"})
+		return
+	}
+
+	response := make([]models.WebhookResponse, len(hooks))
+	for i, hook := range hooks {
+		response[i] = webhookResponse(hook)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateWebhook registers a URL to receive the board's events. The secret that
+// signs deliveries is returned only here.
+func CreateWebhook(c *gin.Context) {
+	board, ok := findOwnedBoard(c, c.Param("boardId"))
+	if !ok {
+		return
+	}
+
+	var input struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+	if err := webhooks.CheckURL(c.Request.Context(), input.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !validWebhookEvents(c, input.Events) {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	userID, _ := c.Get("userID")
+	hook := models.Webhook{
+		ID:        uuid.NewString(),
+		BoardID:   board.ID,
+		CreatorID: userID.(string),
+		URL:       input.URL,
+		Secret:    secret,
+		Events:    input.Events,
+		Active:    true,
+	}
+	if err := config.DB.Omit("Board", "Creator").Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+
+	response := webhookResponse(hook)
+	response.Secret = hook.Secret
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateWebhook changes a webhook's URL, event filter or whether it is active.
+// Fields left out keep their value.
+func UpdateWebhook(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		URL    *string   `json:"url"`
+		Events *[]string `json:"events"`
+		Active *bool     `json:"active"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if input.URL != nil {
+		if err := webhooks.CheckURL(c.Request.Context(), *input.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		hook.URL = *input.URL
+	}
+	if input.Events != nil {
+		if !validWebhookEvents(c, *input.Events) {
+			return
+		}
+		hook.Events = *input.Events
+	}
+	if input.Active != nil {
+		hook.Active = *input.Active
+	}
+
+	if err := config.DB.Model(&hook).Select("url", "events", "active").Updates(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, webhookResponse(hook))
+}
+
+// DeleteWebhook removes a webhook along with its delivery log
+func DeleteWebhook(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := config.DB.Delete(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// GetWebhookDeliveries returns a page of a webhook's delivery log, newest
+// first. "status" keeps only pending, succeeded or failed deliveries.
+func GetWebhookDeliveries(c *gin.Context) {
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	limit, ok := pageLimit(c)
+	if !ok {
+		return
+	}
+
+	query := config.DB.Where("webhook_id = ?", hook.ID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if before := c.Query("before"); before != "" {
+		var cursor models.WebhookDelivery
+		if err := config.DB.Where("id = ? AND webhook_id = ?", before, hook.ID).First(&cursor).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+	}
+
+	// One extra row tells whether there is another page
+	var deliveries []models.WebhookDelivery
+	if err := query.Omit("payload").Order("created_at DESC, id DESC").Limit(limit + 1).
+		Find(&deliveries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get deliveries"})
+		return
+	}
+
+	page := models.WebhookDeliveryPage{Deliveries: []models.WebhookDeliveryResponse{}}
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+		page.NextCursor = deliveries[limit-1].ID
+	}
+	for _, delivery := range deliveries {
+		page.Deliveries = append(page.Deliveries, deliveryResponse(delivery))
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+// GetWebhookDelivery returns one delivery with its payload and every attempt at sending it
+func GetWebhookDelivery(c *gin.Context) {
+	delivery, ok := findWebhookDelivery(c)
+	if !ok {
+		return
+	}
+
+	var attempts []models.WebhookAttempt
+	if err := config.DB.Where("delivery_id = ?", delivery.ID).Order("created_at, id").Find(&attempts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get delivery attempts"})
+		return
+	}
+
+	response := deliveryResponse(delivery)
+	response.Payload = json.RawMessage(delivery.Payload)
+	response.AttemptLog = make([]models.WebhookAttemptResponse, len(attempts))
+	for i, attempt := range attempts {
+		response.AttemptLog[i] = models.WebhookAttemptResponse{
+			ResponseCode: attempt.ResponseCode,
+			Error:        attempt.Error,
+			DurationMs:   attempt.DurationMs,
+			CreatedAt:    attempt.CreatedAt,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ReplayWebhookDelivery sends the payload of a delivery again, as a new delivery
+func ReplayWebhookDelivery(c *gin.Context) {
+	delivery, ok := findWebhookDelivery(c)
+	if !ok {
+		return
+	}
+	if !delivery.Webhook.Active {
+		c.JSON(http.StatusConflict, gin.H{"error": "Webhook is disabled"})
+		return
+	}
+
+	replay, err := webhooks.Replay(delivery)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to replay delivery"})
+		return
+	}
+	c.JSON(http.StatusAccepted, deliveryResponse(replay))
+}
+
+// validWebhookEvents checks that every type in a filter is a board event
+func validWebhookEvents(c *gin.Context, types []string) bool {
+	for _, eventType := range types {
+		if !slices.Contains(events.Types, eventType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type: " + eventType})
+			return false
+		}
+	}
+	return true
+}
+
+// findWebhook loads the webhook in the path if the current user owns its board
+func findWebhook(c *gin.Context) (models.Webhook, bool) {
+	var hook models.Webhook
+	board, ok := findOwnedBoard(c, c.Param("boardId"))
+	if !ok {
+		return hook, false
+	}
+
+	if err := config.DB.Where("id = ? AND board_id = ?", c.Param("webhookId"), board.ID).First(&hook).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return hook, false
+	}
+	return hook, true
+}
+
+// findWebhookDelivery loads the delivery in the path, with its webhook
+func findWebhookDelivery(c *gin.Context) (models.WebhookDelivery, bool) {
+	var delivery models.WebhookDelivery
+	hook, ok := findWebhook(c)
+	if !ok {
+		return delivery, false
+	}
+
+	if err := config.DB.Where("id = ? AND webhook_id = ?", c.Param("deliveryId"), hook.ID).
+		First(&delivery).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Delivery not found"})
+		return delivery, false
+	}
+	delivery.Webhook = hook
+	return delivery, true
+}
+
+func webhookResponse(hook models.Webhook) models.WebhookResponse {
+	types := hook.Events
+	if types == nil {
+		types = []string{}
+	}
+	return models.WebhookResponse{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    types,
+		Active:    hook.Active,
+		CreatedAt: hook.CreatedAt,
+	}
+}
+
+func deliveryResponse(delivery models.WebhookDelivery) models.WebhookDeliveryResponse {
+	return models.WebhookDeliveryResponse{
+		ID:            delivery.ID,
+		WebhookID:     delivery.WebhookID,
+		EventType:     delivery.EventType,
+		Status:        delivery.Status,
+		Attempts:      delivery.Attempts,
+		ResponseCode:  delivery.ResponseCode,
+		Error:         delivery.Error,
+		NextAttemptAt: delivery.NextAttemptAt,
+		ReplayOf:      delivery.ReplayOf,
+		CreatedAt:     delivery.CreatedAt,
+		DeliveredAt:   delivery.DeliveredAt,
+	}
+}
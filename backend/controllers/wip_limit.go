@@ -0,0 +1,47 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"trello-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// errWIPLimitReached is returned when a card is added to a full list on a board
+// in hard WIP mode
+var errWIPLimitReached = errors.New("list has reached its WIP limit")
+
+// checkWIPLimit is called when card is added to list, whose Board must be loaded
+// and whose row must be locked. When the list is full it adds a warning to the
+// card, or returns errWIPLimitReached in hard mode. Archived cards don't count.
+func checkWIPLimit(tx *gorm.DB, card *models.Card, list models.List) error {
+	if list.WIPLimit == nil {
+		return nil
+	}
+
+	var count int64
+	if err := tx.Model(&models.Card{}).
+		Where("list_id = ? AND archived_at IS NULL AND id <> ?", list.ID, card.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count < int64(*list.WIPLimit) {
+		return nil
+	}
+
+	if list.Board.WIPMode == models.WIPModeHard {
+		return errWIPLimitReached
+	}
+	card.Warnings = append(card.Warnings,
+		fmt.Sprintf("List %q is over its WIP limit (%d/%d)", list.Name, count+1, *list.WIPLimit))
+	return nil
+}
+
+// validWIPLimit checks a WIP limit sent by a client; nil means no limit
+func validWIPLimit(limit *int) error {
+	if limit != nil && *limit < 1 {
+		return errors.New("wipLimit must be at least 1")
+	}
+	return nil
+}
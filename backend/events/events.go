@@ -0,0 +1,91 @@
+// Package events carries board changes to the clients watching a board live.
+// Handlers publish an Event once their change is committed; the stream
+// endpoints subscribe to a board and relay what arrives.
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types pushed to board streams
+const (
+	BoardUpdated = "board.updated"
+
+	ListCreated = "list.created"
+	ListUpdated = "list.updated"
+	ListMoved   = "list.moved"
+	ListDeleted = "list.deleted"
+	CardCreated = "card.created"
+	CardUpdated = "card.updated"
+	CardMoved   = "card.moved"
+	CardDeleted = "card.deleted"
+
+	CardAssigned   = "card.assigned"
+	CommentCreated = "comment.created"
+	MentionCreated = "mention.created"
+	MemberAdded    = "member.added"
+	LabelAdded     = "label.added"
+	// CardDue is published by the scheduler when a card reaches its due date
+	CardDue = "card.due"
+)
+
+// Types lists every event type, for validating subscriptions to them
+var Types = []string{
+	BoardUpdated,
+	ListCreated, ListUpdated, ListMoved, ListDeleted,
+	CardCreated, CardUpdated, CardMoved, CardDeleted,
+	CardAssigned, CommentCreated, MentionCreated, MemberAdded, LabelAdded, CardDue,
+}
+
+// Event is a change on a board. IDs are assigned by the broker and increase
+// with every event, so a client can resume after the last ID it saw.
+type Event struct {
+	ID      int64           `json:"id"`
+	BoardID string          `json:"boardId"`
+	Type    string          `json:"type"`
+	ActorID string          `json:"actorId"`
+	Data    json.RawMessage `json:"data"`
+	At      time.Time       `json:"at"`
+	// Rules lists the automation rules whose actions led to this event, in the
+	// order they ran. It is empty for changes people make.
+	Rules []string `json:"rules,omitempty"`
+}
+
+// New builds an event, encoding data as its payload
+func New(boardID, eventType, actorID string, data interface{}) (Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		BoardID: boardID,
+		Type:    eventType,
+		ActorID: actorID,
+		Data:    payload,
+		At:      time.Now().UTC(),
+	}, nil
+}
+
+// Broker fans events out to the subscribers of each board
+type Broker interface {
+	// Publish assigns the event an ID and delivers it to the board's subscribers
+	Publish(event Event) error
+	// Subscribe starts receiving a board's events. Events after lastEventID that
+	// are still in the broker's history are replayed first; pass 0 to skip that.
+	// The channel is closed when the subscriber falls too far behind or calls
+	// the returned cancel function.
+	Subscribe(boardID string, lastEventID int64) (<-chan Event, func())
+}
+
+var defaultBroker Broker = NewMemoryBroker()
+
+// Default returns the broker shared by the handlers
+func Default() Broker {
+	return defaultBroker
+}
+
+// SetDefault replaces the shared broker. It must be called before the server starts.
+func SetDefault(broker Broker) {
+	defaultBroker = broker
+}
@@ -0,0 +1,43 @@
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// Handler reacts to an event published by this instance
+type Handler func(Event)
+
+var (
+	handlersMu sync.RWMutex
+	handlers   []Handler
+)
+
+// Handle registers a handler for every event published from now on. Handlers
+// run once per event, on the instance that published it, so they can have side
+// effects such as sending notifications without repeating them on every replica.
+func Handle(handler Handler) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	handlers = append(handlers, handler)
+}
+
+// Publish sends an event to the board's live streams through the default broker
+// and runs the registered handlers in the background
+func Publish(event Event) error {
+	err := Default().Publish(event)
+
+	handlersMu.RLock()
+	defer handlersMu.RUnlock()
+	for _, handler := range handlers {
+		go func(handler Handler) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Event handler panic on %s: %v", event.Type, r)
+				}
+			}()
+			handler(event)
+		}(handler)
+	}
+	return err
+}
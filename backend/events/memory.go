@@ -0,0 +1,115 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// historySize is how many recent events are kept for resuming streams
+	historySize = 1024
+	// subscriberBuffer is how many events may queue up for a slow subscriber
+	// before it is dropped and has to reconnect
+	subscriberBuffer = 64
+)
+
+// hub delivers events that already have an ID to local subscribers and keeps
+// a short history of them for replays
+type hub struct {
+	mu          sync.Mutex
+	history     []Event
+	subscribers map[string]map[chan Event]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subscribers: make(map[string]map[chan Event]struct{})}
+}
+
+// deliver records event and sends it to the board's subscribers. Subscribers
+// whose buffer is full are dropped rather than slowing everyone down.
+func (h *hub) deliver(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = append(h.history, event)
+	if len(h.history) > historySize {
+		h.history = h.history[len(h.history)-historySize:]
+	}
+
+	for ch := range h.subscribers[event.BoardID] {
+		select {
+		case ch <- event:
+		default:
+			h.remove(event.BoardID, ch)
+		}
+	}
+}
+
+func (h *hub) Subscribe(boardID string, lastEventID int64) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	if lastEventID > 0 {
+		for _, event := range h.history {
+			if event.BoardID != boardID || event.ID <= lastEventID {
+				continue
+			}
+			select {
+			case ch <- event:
+			default:
+				// Too much was missed to replay; the client has to refetch
+				close(ch)
+				return ch, func() {}
+			}
+		}
+	}
+
+	if h.subscribers[boardID] == nil {
+		h.subscribers[boardID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[boardID][ch] = struct{}{}
+
+	cancel := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.remove(boardID, ch)
+	}
+	return ch, cancel
+}
+
+// remove unsubscribes ch; h.mu must be held
+func (h *hub) remove(boardID string, ch chan Event) {
+	if _, ok := h.subscribers[boardID][ch]; !ok {
+		return
+	}
+	delete(h.subscribers[boardID], ch)
+	if len(h.subscribers[boardID]) == 0 {
+		delete(h.subscribers, boardID)
+	}
+	close(ch)
+}
+
+// MemoryBroker delivers events within this process only
+type MemoryBroker struct {
+	*hub
+	idMu   sync.Mutex
+	lastID int64
+}
+
+// NewMemoryBroker returns an empty in-process broker. IDs start from the
+// current time, so they keep increasing across restarts.
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{hub: newHub(), lastID: time.Now().UnixMicro()}
+}
+
+func (b *MemoryBroker) Publish(event Event) error {
+	// Assigning the ID and delivering under one lock keeps history in ID order
+	b.idMu.Lock()
+	defer b.idMu.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	b.deliver(event)
+	return nil
+}
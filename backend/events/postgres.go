@@ -0,0 +1,223 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+	"trello-backend/models"
+
+	"github.com/jackc/pgx/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	notifyChannel = "board_events"
+	// maxNotifyPayload keeps payloads under Postgres' 8000 byte NOTIFY limit
+	maxNotifyPayload = 7900
+	// eventRetention is how long published events stay in the event table
+	eventRetention = time.Hour
+	maxBackoff     = 30 * time.Second
+	// catchUpWindow is how far below the last delivered ID catching up starts
+	// reading. IDs are taken before the publishing transaction commits, so one
+	// publisher can commit a lower ID after another's higher ID was delivered.
+	// It stays well under historySize so that everything it re-reads that was
+	// delivered already is still in the seen set.
+	catchUpWindow = 256
+)
+
+// PostgresBroker publishes events with NOTIFY so that every instance, each
+// running Listen, relays them to its own subscribers. Events are also written to
+// the board_events table: when an event is too large for NOTIFY only its ID is
+// sent, and listeners read the rest from the table. The table is also how a
+// listener catches up after its connection drops.
+type PostgresBroker struct {
+	*hub
+	db  *gorm.DB
+	dsn string
+
+	mu     sync.Mutex
+	lastID int64              // highest ID delivered, near where catching up starts
+	seen   map[int64]struct{} // recently delivered IDs, so catch-up doesn't repeat them
+	order  []int64            // seen IDs, oldest first, to bound the set
+}
+
+// NewPostgresBroker returns a broker that publishes through db and listens on a
+// separate connection opened from dsn
+func NewPostgresBroker(db *gorm.DB, dsn string) *PostgresBroker {
+	return &PostgresBroker{hub: newHub(), db: db, dsn: dsn, seen: make(map[int64]struct{})}
+}
+
+func (b *PostgresBroker) Publish(event Event) error {
+	return b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw("SELECT nextval(pg_get_serial_sequence('board_events', 'id'))").Scan(&event.ID).Error; err != nil {
+			return err
+		}
+
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(&models.BoardEvent{
+			ID:        event.ID,
+			BoardID:   event.BoardID,
+			Payload:   string(payload),
+			CreatedAt: event.At,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Notifications are sent when the transaction commits, by which time the row is readable
+		notice := string(payload)
+		if len(notice) > maxNotifyPayload {
+			notice = fmt.Sprintf(`{"id":%d}`, event.ID)
+		}
+		return tx.Exec("SELECT pg_notify(?, ?)", notifyChannel, notice).Error
+	})
+}
+
+// Listen relays events published by any instance to local subscribers until
+// ctx is done. Dropped connections are retried with backoff, and the events
+// published in the meantime are read from the event table.
+func (b *PostgresBroker) Listen(ctx context.Context) {
+	go b.pruneEvents(ctx)
+
+	backoff := time.Second
+	for {
+		err := b.listen(ctx, func() { backoff = time.Second })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Event listener: %v; reconnecting in %s", err, backoff)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// listen holds one LISTEN connection until it fails. connected is called once
+// the connection is listening.
+func (b *PostgresBroker) listen(ctx context.Context, connected func()) error {
+	config, err := pgx.ParseConfig(b.dsn)
+	if err != nil {
+		return err
+	}
+	// Meant for the GORM driver; Postgres itself doesn't know it
+	delete(config.RuntimeParams, "prefer_simple_protocol")
+
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return err
+	}
+	connected()
+
+	// Listening comes first, so nothing published from here on can be missed
+	if err := b.catchUp(); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		if err := b.receive(notification.Payload); err != nil {
+			log.Printf("Event listener: dropping notification: %v", err)
+		}
+	}
+}
+
+// catchUp delivers the stored events that haven't been delivered yet, up to the
+// size of the replay history. It re-reads the catchUpWindow IDs before the last
+// one delivered, for events that committed out of order, and accept skips the
+// ones among them that were delivered already.
+func (b *PostgresBroker) catchUp() error {
+	b.mu.Lock()
+	after := max(b.lastID-catchUpWindow, 0)
+	b.mu.Unlock()
+
+	var rows []models.BoardEvent
+	if err := b.db.Where("id > ?", after).Order("id DESC").Limit(historySize).Find(&rows).Error; err != nil {
+		return err
+	}
+
+	for i := len(rows) - 1; i >= 0; i-- {
+		var event Event
+		if err := json.Unmarshal([]byte(rows[i].Payload), &event); err != nil {
+			return err
+		}
+		b.accept(event)
+	}
+	return nil
+}
+
+// receive decodes a notification, reading the event from the table when only
+// its ID was sent
+func (b *PostgresBroker) receive(payload string) error {
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return err
+	}
+	if event.Type != "" {
+		b.accept(event)
+		return nil
+	}
+
+	var row models.BoardEvent
+	if err := b.db.Where("id = ?", event.ID).First(&row).Error; err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(row.Payload), &event); err != nil {
+		return err
+	}
+	b.accept(event)
+	return nil
+}
+
+// accept delivers an event unless it was delivered already
+func (b *PostgresBroker) accept(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.seen[event.ID]; ok {
+		return
+	}
+	b.seen[event.ID] = struct{}{}
+	b.order = append(b.order, event.ID)
+	if len(b.order) > historySize {
+		delete(b.seen, b.order[0])
+		b.order = b.order[1:]
+	}
+	b.lastID = max(b.lastID, event.ID)
+
+	b.deliver(event)
+}
+
+// pruneEvents removes events older than eventRetention until ctx is done
+func (b *PostgresBroker) pruneEvents(ctx context.Context) {
+	ticker := time.NewTicker(eventRetention / 4)
+	defer ticker.Stop()
+	for {
+		if err := b.db.Where("created_at < ?", time.Now().UTC().Add(-eventRetention)).
+			Delete(&models.BoardEvent{}).Error; err != nil {
+			log.Printf("Event pruning: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
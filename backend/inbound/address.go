@@ -0,0 +1,78 @@
+// Package inbound turns emails into cards and comments. It builds the
+// addresses mail is sent to and parses the MIME messages that arrive there.
+package inbound
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strings"
+	"trello-backend/middlewares"
+)
+
+// Kinds of inbound address
+const (
+	// KindBoard addresses turn each email into a card on the board
+	KindBoard = "board"
+	// KindReply addresses turn replies to a notification into comments on the card
+	KindReply = "reply"
+)
+
+// Domain is the mail domain that receives inbound email, read from
+// INBOUND_EMAIL_DOMAIN. Inbound email is off when it is empty.
+func Domain() string {
+	return strings.ToLower(os.Getenv("INBOUND_EMAIL_DOMAIN"))
+}
+
+// BoardAddress returns the address that creates cards on a board, or "" when inbound email is off
+func BoardAddress(boardID string) string {
+	return address(KindBoard, boardID)
+}
+
+// ReplyAddress returns the address replies about a card go to, or "" when inbound email is off
+func ReplyAddress(cardID string) string {
+	return address(KindReply, cardID)
+}
+
+// address builds kind+id.signature@domain. The signature keeps people from
+// guessing the address of a board or card from its ID.
+func address(kind, id string) string {
+	domain := Domain()
+	if domain == "" {
+		return ""
+	}
+	return kind + "+" + id + "." + sign(kind, id) + "@" + domain
+}
+
+// ParseAddress returns the kind and ID of an address made by BoardAddress or
+// ReplyAddress. It reports false for any other address.
+func ParseAddress(addr string) (kind, id string, ok bool) {
+	domain := Domain()
+	local, host, found := strings.Cut(strings.ToLower(addr), "@")
+	if domain == "" || !found || host != domain {
+		return "", "", false
+	}
+
+	kind, rest, found := strings.Cut(local, "+")
+	if !found || (kind != KindBoard && kind != KindReply) {
+		return "", "", false
+	}
+	dot := strings.LastIndex(rest, ".")
+	if dot < 0 {
+		return "", "", false
+	}
+	id, signature := rest[:dot], rest[dot+1:]
+	if !hmac.Equal([]byte(signature), []byte(sign(kind, id))) {
+		return "", "", false
+	}
+	return kind, id, true
+}
+
+// sign returns a short MAC of an address, short enough to keep the local part
+// of the address within 64 characters
+func sign(kind, id string) string {
+	mac := hmac.New(sha256.New, middlewares.JwtSecret)
+	mac.Write([]byte("inbound:" + kind + ":" + id))
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
@@ -0,0 +1,75 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+	"trello-backend/middlewares"
+)
+
+func setUpAddresses(t *testing.T) {
+	t.Setenv("INBOUND_EMAIL_DOMAIN", "In.Example.com")
+	previous := middlewares.JwtSecret
+	middlewares.JwtSecret = []byte("test secret")
+	t.Cleanup(func() { middlewares.JwtSecret = previous })
+}
+
+func TestAddressRoundTrip(t *testing.T) {
+	setUpAddresses(t)
+
+	for _, tt := range []struct{ kind, address string }{
+		{KindBoard, BoardAddress("b6a1c1e4-4c4f-4f4e-9d1b-2f0b5d1e7c11")},
+		{KindReply, ReplyAddress("c0ffee00-1234-4567-89ab-cdef01234567")},
+	} {
+		if !strings.HasSuffix(tt.address, "@in.example.com") {
+			t.Fatalf("address %q is not on the inbound domain", tt.address)
+		}
+		// Mail servers may change the case of the address
+		kind, id, ok := ParseAddress(strings.ToUpper(tt.address))
+		if !ok || kind != tt.kind || !strings.HasPrefix(tt.address, kind+"+"+id+".") {
+			t.Errorf("ParseAddress(%q) = %q, %q, %v", tt.address, kind, id, ok)
+		}
+	}
+}
+
+func TestParseAddressRejectsForgeries(t *testing.T) {
+	setUpAddresses(t)
+	board := BoardAddress("board-1")
+	local, _, _ := strings.Cut(board, "@")
+	_, signature, _ := strings.Cut(local, ".")
+
+	forged := []string{
+		// Someone else's board with this board's signature
+		"board+board-2." + signature + "@in.example.com",
+		// The same board as a reply address
+		"reply+board-1." + signature + "@in.example.com",
+		// A tampered signature
+		"board+board-1." + strings.Repeat("0", len(signature)) + "@in.example.com",
+		"board+board-1@in.example.com",
+		strings.Replace(board, "@in.example.com", "@elsewhere.example.com", 1),
+		"alice@in.example.com",
+		"not an address",
+	}
+	for _, address := range forged {
+		if kind, id, ok := ParseAddress(address); ok {
+			t.Errorf("ParseAddress(%q) accepted %s %s", address, kind, id)
+		}
+	}
+
+	// A signature made with another key doesn't carry over
+	middlewares.JwtSecret = []byte("another secret")
+	if _, _, ok := ParseAddress(board); ok {
+		t.Error("ParseAddress accepted an address signed with another key")
+	}
+}
+
+func TestAddressesOffWithoutDomain(t *testing.T) {
+	setUpAddresses(t)
+	address := BoardAddress("board-1")
+	t.Setenv("INBOUND_EMAIL_DOMAIN", "")
+	if BoardAddress("board-1") != "" {
+		t.Error("BoardAddress made an address with inbound email off")
+	}
+	if _, _, ok := ParseAddress(address); ok {
+		t.Error("ParseAddress accepted an address with inbound email off")
+	}
+}
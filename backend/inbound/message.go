@@ -0,0 +1,194 @@
+package inbound
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// ReplyMarker is put at the top of notification emails that can be replied to.
+// Everything from it down is the quoted notification and is dropped.
+const ReplyMarker = "## Reply above this line to comment ##"
+
+// maxPartDepth limits how deeply multipart bodies may nest
+const maxPartDepth = 10
+
+// Message is the part of an inbound email used to make a card or comment
+type Message struct {
+	From        string
+	Subject     string
+	Text        string
+	Recipients  []string
+	Attachments []Attachment
+}
+
+// Attachment is a file attached to an inbound email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+var errNoSender = errors.New("message has no sender")
+
+var wordDecoder = mime.WordDecoder{
+	// Only UTF-8 and ASCII are decoded; other charsets are passed through
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	},
+}
+
+// Parse reads a raw MIME message. The body is its plain-text part, or its
+// HTML part stripped of markup when there is no plain-text one.
+func Parse(r io.Reader) (*Message, error) {
+	raw, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := mail.ParseAddress(raw.Header.Get("From"))
+	if err != nil {
+		return nil, errNoSender
+	}
+	subject, err := wordDecoder.DecodeHeader(raw.Header.Get("Subject"))
+	if err != nil {
+		subject = raw.Header.Get("Subject")
+	}
+
+	msg := &Message{From: strings.ToLower(from.Address), Subject: strings.TrimSpace(subject)}
+	for _, key := range []string{"To", "Cc", "Delivered-To", "X-Original-To"} {
+		addresses, err := mail.ParseAddressList(raw.Header.Get(key))
+		if err != nil {
+			continue
+		}
+		for _, address := range addresses {
+			msg.Recipients = append(msg.Recipients, strings.ToLower(address.Address))
+		}
+	}
+
+	var text, htmlText string
+	if err := walkPart(msg, raw.Header, raw.Body, &text, &htmlText, 0); err != nil {
+		return nil, err
+	}
+	if text == "" && htmlText != "" {
+		text = stripHTML(htmlText)
+	}
+	msg.Text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	return msg, nil
+}
+
+// walkPart reads one MIME part, descending into multipart bodies. The first
+// plain-text and HTML parts become the body; parts with a filename are attachments.
+func walkPart(msg *Message, header mail.Header, body io.Reader, text, htmlText *string, depth int) error {
+	if depth > maxPartDepth {
+		return fmt.Errorf("message nests more than %d parts deep", maxPartDepth)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := walkPart(msg, mail.Header(part.Header), part, text, htmlText, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransfer(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	filename := ""
+	if _, dispositionParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil {
+		filename = dispositionParams["filename"]
+	}
+	if filename == "" {
+		filename = params["name"]
+	}
+	if decoded, err := wordDecoder.DecodeHeader(filename); err == nil {
+		filename = decoded
+	}
+
+	switch {
+	case filename != "":
+		msg.Attachments = append(msg.Attachments, Attachment{Filename: filename, ContentType: mediaType, Data: data})
+	case mediaType == "text/plain" && *text == "":
+		*text = string(data)
+	case mediaType == "text/html" && *htmlText == "":
+		*htmlText = string(data)
+	}
+	return nil
+}
+
+func decodeTransfer(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+var (
+	blockTags  = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/h[1-6]|/tr)[^>]*>`)
+	allTags    = regexp.MustCompile(`(?s)<[^>]*>`)
+	hiddenTags = regexp.MustCompile(`(?is)<(style|script|head)[^>]*>.*?</(style|script|head)>`)
+	blankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// stripHTML turns an HTML body into text, keeping line breaks
+func stripHTML(body string) string {
+	body = hiddenTags.ReplaceAllString(body, "")
+	body = blockTags.ReplaceAllString(body, "\n")
+	body = allTags.ReplaceAllString(body, "")
+	body = html.UnescapeString(body)
+
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+}
+
+var quoteHeader = regexp.MustCompile(`(?m)^On .*wrote:\s*$`)
+
+// StripReply returns just the new text of a reply: what comes before the reply
+// marker, the "On ... wrote:" line of the quoted message, or the first quoted line
+func StripReply(text string) string {
+	if i := strings.Index(text, ReplyMarker); i >= 0 {
+		text = text[:i]
+	}
+	if loc := quoteHeader.FindStringIndex(text); loc != nil {
+		text = text[:loc[0]]
+	}
+
+	var kept []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, ">") {
+			break
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,139 @@
+package inbound
+
+import (
+	"strings"
+	"testing"
+)
+
+// crlf turns a message written with \n line endings into one with \r\n, as it arrives
+func crlf(message string) string {
+	return strings.ReplaceAll(message, "\n", "\r\n")
+}
+
+func TestParseMultipartAlternativePrefersPlainText(t *testing.T) {
+	msg, err := Parse(strings.NewReader(crlf(`From: "Alice" <Alice@Example.com>
+To: board+1.abc@in.example.com
+Cc: Bob <bob@example.com>
+Subject: =?UTF-8?Q?Caf=C3=A9_order?=
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain; charset=utf-8
+
+Two espressos, please.
+--alt
+Content-Type: text/html; charset=utf-8
+
+<p>Two <b>espressos</b>, please.</p>
+--alt--
+`)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if msg.From != "alice@example.com" {
+		t.Errorf("From = %q", msg.From)
+	}
+	if msg.Subject != "Café order" {
+		t.Errorf("Subject = %q", msg.Subject)
+	}
+	if msg.Text != "Two espressos, please." {
+		t.Errorf("Text = %q", msg.Text)
+	}
+	if strings.Join(msg.Recipients, ",") != "board+1.abc@in.example.com,bob@example.com" {
+		t.Errorf("Recipients = %v", msg.Recipients)
+	}
+}
+
+func TestParseFallsBackToStrippedHTML(t *testing.T) {
+	msg, err := Parse(strings.NewReader(crlf(`From: alice@example.com
+Content-Type: text/html
+
+<html><head><style>p { color: red }</style></head><body><p>First &amp; second</p><div>Third</div></body></html>
+`)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if msg.Text != "First & second\nThird" {
+		t.Errorf("Text = %q", msg.Text)
+	}
+}
+
+func TestParseDecodesQuotedPrintable(t *testing.T) {
+	msg, err := Parse(strings.NewReader(crlf(`From: alice@example.com
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+The d=C3=A9j=C3=A0 vu line is long enough that it gets a soft line brea=
+k in the middle.
+`)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if msg.Text != "The déjà vu line is long enough that it gets a soft line break in the middle." {
+		t.Errorf("Text = %q", msg.Text)
+	}
+}
+
+func TestParseCollectsAttachments(t *testing.T) {
+	msg, err := Parse(strings.NewReader(crlf(`From: alice@example.com
+Content-Type: multipart/mixed; boundary="mixed"
+
+--mixed
+Content-Type: multipart/alternative; boundary="alt"
+
+--alt
+Content-Type: text/plain
+
+See attached.
+--alt--
+--mixed
+Content-Type: application/pdf; name="ignored.pdf"
+Content-Disposition: attachment; filename="=?UTF-8?Q?r=C3=A9sum=C3=A9.pdf?="
+Content-Transfer-Encoding: base64
+
+JVBERi0xLjQ=
+--mixed
+Content-Type: text/plain; name="notes.txt"
+Content-Transfer-Encoding: quoted-printable
+
+a=3Db
+--mixed--
+`)))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if msg.Text != "See attached." {
+		t.Errorf("Text = %q", msg.Text)
+	}
+	if len(msg.Attachments) != 2 {
+		t.Fatalf("got %d attachments, want 2", len(msg.Attachments))
+	}
+	pdf, notes := msg.Attachments[0], msg.Attachments[1]
+	if pdf.Filename != "résumé.pdf" || pdf.ContentType != "application/pdf" || string(pdf.Data) != "%PDF-1.4" {
+		t.Errorf("first attachment = %q %q %q", pdf.Filename, pdf.ContentType, pdf.Data)
+	}
+	if notes.Filename != "notes.txt" || string(notes.Data) != "a=b" {
+		t.Errorf("second attachment = %q %q", notes.Filename, notes.Data)
+	}
+}
+
+func TestParseRejectsMissingSender(t *testing.T) {
+	if _, err := Parse(strings.NewReader(crlf("Subject: hi\n\nbody\n"))); err == nil {
+		t.Fatal("Parse accepted a message without a sender")
+	}
+}
+
+func TestStripReply(t *testing.T) {
+	tests := map[string]string{
+		"Done, thanks!\n\n" + ReplyMarker + "\nAlice commented on Fix login":             "Done, thanks!",
+		"Looks good\n\nOn Mon, 2 Nov 2026 at 09:00, Alice <a@example.com> wrote:\n> old": "Looks good",
+		"Agreed\n> quoted\nmore":       "Agreed",
+		"No quote at all\nsecond line": "No quote at all\nsecond line",
+	}
+	for text, want := range tests {
+		if got := StripReply(text); got != want {
+			t.Errorf("StripReply(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
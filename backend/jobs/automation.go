@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"log"
+	"slices"
+	"time"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// dueTriggerLockKey is the Postgres advisory lock that lets only one replica
+// look for due cards at a time
+const dueTriggerLockKey = 726003
+
+// dueTriggerWindow is how long after its due date a card can still fire the
+// trigger, so a new rule doesn't act on every long-overdue card
+const dueTriggerWindow = 24 * time.Hour
+
+// StartDueDateTrigger publishes a card.due event for each card that reaches its
+// due date on a board with a due-date rule, until the process exits.
+// AUTOMATION_DUE_INTERVAL sets how often it looks.
+func StartDueDateTrigger() {
+	interval := durationFromEnv("AUTOMATION_DUE_INTERVAL", time.Minute)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := triggerDueDates(time.Now().UTC()); err != nil {
+			log.Printf("Due date trigger: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// triggerDueDates claims the cards that have come due by now and publishes
+// their events. Each due date fires once; a card whose due date changes fires again.
+func triggerDueDates(now time.Time) error {
+	var due []models.Card
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", dueTriggerLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			// Another replica is already handling this run
+			return nil
+		}
+
+		// Triggers are stored as JSON, so the boards are picked out here
+		var rules []models.AutomationRule
+		if err := tx.Select("board_id", "trigger").Where("enabled = ?", true).Find(&rules).Error; err != nil {
+			return err
+		}
+		var boardIDs []string
+		for _, rule := range rules {
+			if rule.Trigger.Type == models.TriggerDueDate && !slices.Contains(boardIDs, rule.BoardID) {
+				boardIDs = append(boardIDs, rule.BoardID)
+			}
+		}
+		if len(boardIDs) == 0 {
+			return nil
+		}
+
+		var cards []models.Card
+		if err := tx.Preload("List").
+			Where("list_id IN (?)", tx.Model(&models.List{}).Select("id").Where("board_id IN ?", boardIDs)).
+			Where("due_complete = ? AND is_template = ? AND archived_at IS NULL AND due_date > ? AND due_date <= ?",
+				false, false, now.Add(-dueTriggerWindow), now).
+			Find(&cards).Error; err != nil {
+			return err
+		}
+
+		for _, card := range cards {
+			claim := models.CardDueTrigger{CardID: card.ID, DueDate: *card.DueDate, TriggeredAt: now}
+			result := tx.Omit("Card").Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 1 {
+				due = append(due, card)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// The scheduler has no user, so the events have no actor
+	for _, card := range due {
+		event, err := events.New(card.List.BoardID, events.CardDue, "", map[string]interface{}{
+			"id": card.ID, "listId": card.ListID, "title": card.Title, "dueDate": card.DueDate,
+		})
+		if err == nil {
+			err = events.Publish(event)
+		}
+		if err != nil {
+			log.Printf("Failed to publish due date of card %s: %v", card.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package jobs
+
+import (
+	"log"
+	"time"
+	"trello-backend/config"
+	"trello-backend/mailer"
+	"trello-backend/models"
+	"trello-backend/notifications"
+
+	"gorm.io/gorm"
+)
+
+// digestLockKey is the Postgres advisory lock that lets only one replica
+// collect digests at a time
+const digestLockKey = 726002
+
+// digestPeriods is how often each digest mode sends a digest
+var digestPeriods = map[string]time.Duration{
+	models.EmailDeliveryDaily:  24 * time.Hour,
+	models.EmailDeliveryWeekly: 7 * 24 * time.Hour,
+}
+
+// StartDigestScheduler sends the daily and weekly email digests until the process exits.
+// DIGEST_INTERVAL sets how often it looks for digests that are due.
+func StartDigestScheduler(m mailer.Mailer) {
+	interval := durationFromEnv("DIGEST_INTERVAL", 15*time.Minute)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := sendDigests(m, time.Now().UTC()); err != nil {
+			log.Printf("Digest scheduler: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+type digest struct {
+	user    models.User
+	entries []models.DigestEntry
+}
+
+// sendDigests claims and sends every digest that is due at now. A digest is due
+// one period after the user's last digest, or after its oldest entry for a first
+// digest. Entries are removed before sending, so each goes out at most once.
+func sendDigests(m mailer.Mailer, now time.Time) error {
+	var pending []digest
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", digestLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			// Another replica is already handling this run
+			return nil
+		}
+
+		var users []models.User
+		if err := tx.Where("id IN (?)", tx.Model(&models.DigestEntry{}).Select("user_id")).
+			Find(&users).Error; err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			var entries []models.DigestEntry
+			if err := tx.Where("user_id = ?", user.ID).Order("created_at, id").Find(&entries).Error; err != nil {
+				return err
+			}
+
+			// Users who switched to immediate email get what was collected right
+			// away; users who turned email off get nothing
+			if period, ok := digestPeriods[user.EmailDelivery]; ok {
+				since := entries[0].CreatedAt
+				if user.LastDigestAt != nil {
+					since = *user.LastDigestAt
+				}
+				if now.Before(since.Add(period)) {
+					continue
+				}
+			}
+
+			if err := tx.Delete(&entries).Error; err != nil {
+				return err
+			}
+			if user.EmailDelivery == models.EmailDeliveryOff {
+				continue
+			}
+			if err := tx.Model(&user).Update("last_digest_at", now).Error; err != nil {
+				return err
+			}
+			pending = append(pending, digest{user, entries})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, d := range pending {
+		if err := notifications.SendDigest(m, d.user, d.entries); err != nil {
+			log.Printf("Failed to send digest to %s: %v", d.user.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package jobs
+
+import (
+	"log"
+	"os"
+	"time"
+)
+
+// durationFromEnv reads a Go duration such as "90s" or "24h" from the environment
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("Warning: invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
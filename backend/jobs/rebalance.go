@@ -0,0 +1,69 @@
+package jobs
+
+import (
+	"log"
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/rank"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StartRankRebalancer respreads the rank keys of any list or board whose keys
+// have grown past rank.MaxLength. RANK_REBALANCE_INTERVAL sets how often it runs.
+func StartRankRebalancer() {
+	interval := durationFromEnv("RANK_REBALANCE_INTERVAL", 10*time.Minute)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := rebalanceLongRanks(); err != nil {
+			log.Printf("Rank rebalancer: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+func rebalanceLongRanks() error {
+	var listIDs []string
+	if err := config.DB.Table("cards").Distinct("list_id").
+		Where("deleted_at IS NULL AND length(rank) > ?", rank.MaxLength).
+		Pluck("list_id", &listIDs).Error; err != nil {
+		return err
+	}
+	for _, listID := range listIDs {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			// Hold the list row so concurrent moves wait for the new keys
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ?", listID).First(&models.List{}).Error; err != nil {
+				return err
+			}
+			return rank.Rebalance(tx, rank.CardsIn(listID))
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	var boardIDs []string
+	if err := config.DB.Table("lists").Distinct("board_id").
+		Where("deleted_at IS NULL AND length(rank) > ?", rank.MaxLength).
+		Pluck("board_id", &boardIDs).Error; err != nil {
+		return err
+	}
+	for _, boardID := range boardIDs {
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ?", boardID).First(&models.Board{}).Error; err != nil {
+				return err
+			}
+			return rank.Rebalance(tx, rank.ListsIn(boardID))
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
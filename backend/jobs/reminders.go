@@ -0,0 +1,131 @@
+package jobs
+
+import (
+	"fmt"
+	"log"
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/notifications"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// reminderLockKey is the Postgres advisory lock that lets only one replica
+// scan for reminders at a time
+const reminderLockKey = 726001
+
+// StartReminderScheduler periodically sends due-date reminders until the process exits.
+// REMINDER_INTERVAL sets how often it runs. It looks as far ahead as the longest
+// lead time a user can choose.
+func StartReminderScheduler(notifier notifications.Notifier) {
+	interval := durationFromEnv("REMINDER_INTERVAL", time.Minute)
+	window := time.Duration(models.MaxReminderLeadMinutes) * time.Minute
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := sendDueReminders(notifier, window); err != nil {
+			log.Printf("Reminder scheduler: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// sendDueReminders claims and delivers every reminder that is due right now.
+// Claims are committed before delivery, so a reminder is sent at most once.
+func sendDueReminders(notifier notifications.Notifier, window time.Duration) error {
+	var pending []notifications.Message
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", reminderLockKey).Scan(&locked).Error; err != nil {
+			return err
+		}
+		if !locked {
+			// Another replica is already handling this run
+			return nil
+		}
+
+		// Cards on archived or trashed lists and boards are as good as archived
+		now := time.Now().UTC()
+		var cards []models.Card
+		if err := tx.Preload("List").
+			Joins("JOIN lists ON lists.id = cards.list_id AND lists.archived_at IS NULL AND lists.deleted_at IS NULL").
+			Joins("JOIN boards ON boards.id = lists.board_id AND boards.archived_at IS NULL AND boards.deleted_at IS NULL").
+			Where("cards.due_complete = ? AND cards.is_template = ? AND boards.is_template = ? AND cards.archived_at IS NULL",
+				false, false, false).
+			Where("cards.due_date > ? AND cards.due_date <= ?", now, now.Add(window)).
+			Find(&cards).Error; err != nil {
+			return err
+		}
+
+		for _, card := range cards {
+			recipients, err := reminderRecipients(tx, card)
+			if err != nil {
+				return err
+			}
+
+			for _, user := range recipients {
+				lead := time.Duration(user.ReminderLeadMinutes) * time.Minute
+				if lead <= 0 || card.DueDate.Sub(now) > lead {
+					continue
+				}
+
+				claim := models.CardReminder{
+					ID:      uuid.NewString(),
+					CardID:  card.ID,
+					UserID:  user.ID,
+					DueDate: *card.DueDate,
+					SentAt:  now,
+				}
+				result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+				if result.Error != nil {
+					return result.Error
+				}
+				if result.RowsAffected == 0 {
+					continue
+				}
+
+				pending = append(pending, notifications.Message{
+					User:    user,
+					Type:    notifications.TypeDueReminder,
+					Title:   fmt.Sprintf("\"%s\" is due soon", card.Title),
+					Body:    fmt.Sprintf("\"%s\" is due %s.", card.Title, card.DueDate.Format(time.RFC1123)),
+					BoardID: card.List.BoardID,
+					CardID:  card.ID,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range pending {
+		if err := notifier.Notify(msg); err != nil {
+			log.Printf("Failed to deliver reminder for card %s to %s: %v", msg.CardID, msg.User.ID, err)
+		}
+	}
+	return nil
+}
+
+// reminderRecipients returns the users who should be reminded about a card:
+// its assignees and the users watching the card itself. The card's List must be
+// loaded. Assignments outlive a member leaving the board, so only members and
+// the owner count, as with subscriptions.Watchers.
+func reminderRecipients(tx *gorm.DB, card models.Card) ([]models.User, error) {
+	var users []models.User
+	err := tx.Where("id IN (?) OR id IN (?)",
+		tx.Table("card_assignees").Select("user_id").Where("card_id = ?", card.ID),
+		tx.Model(&models.Subscription{}).Select("user_id").
+			Where("entity_type = ? AND entity_id = ?", models.EntityCard, card.ID)).
+		Where("id IN (?) OR id IN (?)",
+			tx.Model(&models.BoardMember{}).Select("user_id").Where("board_id = ?", card.List.BoardID),
+			tx.Model(&models.Board{}).Select("owner_id").Where("id = ?", card.List.BoardID)).
+		Find(&users).Error
+	return users, err
+}
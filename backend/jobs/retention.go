@@ -0,0 +1,57 @@
+package jobs
+
+import (
+	"database/sql"
+	"log"
+	"time"
+	"trello-backend/config"
+
+	"gorm.io/gorm"
+)
+
+const (
+	expiredBoards = `SELECT id FROM boards WHERE deleted_at < @cutoff`
+	expiredLists  = `SELECT id FROM lists WHERE deleted_at < @cutoff OR board_id IN (` + expiredBoards + `)`
+	expiredCards  = `SELECT id FROM cards WHERE deleted_at < @cutoff OR list_id IN (` + expiredLists + `)`
+)
+
+// StartTrashRetention permanently removes trashed items once they are older
+// than config.TrashRetention. TRASH_PURGE_INTERVAL sets how often it runs.
+func StartTrashRetention() {
+	interval := durationFromEnv("TRASH_PURGE_INTERVAL", time.Hour)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		cutoff := time.Now().Add(-config.TrashRetention())
+		if err := purgeTrash(cutoff); err != nil {
+			log.Printf("Trash retention: %v", err)
+		}
+		<-ticker.C
+	}
+}
+
+// purgeTrash hard-deletes everything trashed before cutoff. The cascading foreign
+// keys remove the lists, cards, members and assignees that belong to purged rows;
+// the activity log has no foreign key to boards and is kept.
+func purgeTrash(cutoff time.Time) error {
+	statements := []string{
+		`DELETE FROM card_reminders WHERE card_id IN (` + expiredCards + `)`,
+		// Subscriptions point at boards, lists and cards without a foreign key
+		`DELETE FROM subscriptions WHERE (entity_type = 'card' AND entity_id IN (` + expiredCards + `))
+			OR (entity_type = 'list' AND entity_id IN (` + expiredLists + `))
+			OR (entity_type = 'board' AND entity_id IN (` + expiredBoards + `))`,
+		`DELETE FROM cards WHERE deleted_at < @cutoff`,
+		`DELETE FROM lists WHERE deleted_at < @cutoff`,
+		`DELETE FROM boards WHERE deleted_at < @cutoff`,
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement, sql.Named("cutoff", cutoff)).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
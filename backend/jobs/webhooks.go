@@ -0,0 +1,23 @@
+package jobs
+
+import (
+	"log"
+	"time"
+	"trello-backend/webhooks"
+)
+
+// StartWebhookRetrier resends failed webhook deliveries once their backoff is
+// over, until the process exits. Each delivery is claimed before it is sent,
+// so every replica can run it. WEBHOOK_RETRY_INTERVAL sets how often it looks.
+func StartWebhookRetrier() {
+	interval := durationFromEnv("WEBHOOK_RETRY_INTERVAL", 15*time.Second)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := webhooks.RetryDue(time.Now().UTC()); err != nil {
+			log.Printf("Webhook retrier: %v", err)
+		}
+		<-ticker.C
+	}
+}
@@ -0,0 +1,124 @@
+// Package mailer sends email. Messages carry a plain-text and an HTML body and
+// go out over SMTP, or into a local sink during development and tests.
+package mailer
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an email to a single recipient
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Headers are added to the standard ones, e.g. List-Unsubscribe
+	Headers map[string]string
+}
+
+// Mailer sends messages
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// Bytes encodes the message as a multipart/alternative MIME message from the given sender
+func (m Message) Bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+	body := multipart.NewWriter(&buf)
+
+	header := textproto.MIMEHeader{}
+	header.Set("From", from)
+	header.Set("To", m.To)
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", m.Subject))
+	header.Set("Date", time.Now().UTC().Format(time.RFC1123Z))
+	header.Set("Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewString(), domainOf(from)))
+	header.Set("MIME-Version", "1.0")
+	header.Set("Content-Type", "multipart/alternative; boundary="+body.Boundary())
+	for key, value := range m.Headers {
+		header.Set(key, value)
+	}
+
+	var out bytes.Buffer
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&out, "%s: %s\r\n", key, header.Get(key))
+	}
+	out.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=UTF-8", m.Text},
+		{"text/html; charset=UTF-8", m.HTML},
+	} {
+		w, err := body.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := body.Close(); err != nil {
+		return nil, err
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+// domainOf returns the domain of an address, for message IDs
+func domainOf(address string) string {
+	parsed, err := mail.ParseAddress(address)
+	if err != nil {
+		return "localhost"
+	}
+	return parsed.Address[strings.LastIndex(parsed.Address, "@")+1:]
+}
+
+var (
+	defaultOnce   sync.Once
+	defaultMailer Mailer
+)
+
+// Default returns the mailer chosen by MAILER: "smtp", which is used when
+// SMTP_HOST is set, or "sink", which keeps messages locally. It returns nil
+// when email is not configured. It is built once and shared.
+func Default() Mailer {
+	defaultOnce.Do(func() {
+		switch os.Getenv("MAILER") {
+		case "sink":
+			defaultMailer = &Sink{Dir: os.Getenv("MAIL_SINK_DIR")}
+		case "", "smtp":
+			if smtp, ok := NewSMTPMailerFromEnv(); ok {
+				defaultMailer = smtp
+			}
+		default:
+			log.Printf("Warning: unknown MAILER %q, email is off", os.Getenv("MAILER"))
+		}
+	})
+	return defaultMailer
+}
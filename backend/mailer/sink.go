@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Sink keeps messages instead of sending them, for local development and tests.
+// When Dir is set, each message is also written there as an .eml file that
+// can be opened in a mail client.
+type Sink struct {
+	Dir string
+
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (s *Sink) Send(msg Message) error {
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	count := len(s.messages)
+	s.mu.Unlock()
+
+	if s.Dir == "" {
+		return nil
+	}
+	data, err := msg.Bytes("no-reply@localhost")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%04d.eml", time.Now().UTC().Format("20060102T150405"), count)
+	return os.WriteFile(filepath.Join(s.Dir, name), data, 0o644)
+}
+
+// Messages returns the messages sent so far, oldest first
+func (s *Sink) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reset forgets the messages sent so far
+func (s *Sink) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
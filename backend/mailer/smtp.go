@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"net/smtp"
+	"os"
+)
+
+// SMTPMailer sends messages through an SMTP server
+type SMTPMailer struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// NewSMTPMailerFromEnv builds an SMTPMailer from the SMTP_* variables.
+// It reports false when SMTP_HOST is not set.
+func NewSMTPMailerFromEnv() (*SMTPMailer, bool) {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		return nil, false
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+	from := os.Getenv("SMTP_FROM")
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+
+	mailer := &SMTPMailer{Addr: host + ":" + port, From: from}
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		mailer.Auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+	return mailer, true
+}
+
+func (s *SMTPMailer) Send(msg Message) error {
+	data, err := msg.Bytes(s.From)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.Addr, s.Auth, s.From, []string{msg.To}, data)
+}
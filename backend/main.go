@@ -1,13 +1,44 @@
 package main
 
 import (
+	"context"
+	"os"
+	"trello-backend/config"
+	"trello-backend/controllers"
+	"trello-backend/events"
+	"trello-backend/jobs"
+	"trello-backend/mailer"
 	"trello-backend/middlewares"
+	"trello-backend/notifications"
 	"trello-backend/routes"
+	"trello-backend/webhooks"
 )
 
 func main() {
 	middlewares.InitJWTSecret()
 	router := routes.SetupRouter()
 
+	// Board events go through Postgres so every replica can relay them to its
+	// clients. EVENT_BROKER=memory keeps them inside this process instead.
+	if os.Getenv("EVENT_BROKER") != "memory" {
+		broker := events.NewPostgresBroker(config.DB, config.ListenDSN())
+		events.SetDefault(broker)
+		go broker.Listen(context.Background())
+	}
+
+	// Notifications, webhook deliveries and automation rules run from the events handlers publish
+	events.Handle(notifications.FromEvent)
+	events.Handle(webhooks.FromEvent)
+	events.Handle(controllers.RunAutomations)
+
+	go jobs.StartReminderScheduler(notifications.Default())
+	if m := mailer.Default(); m != nil {
+		go jobs.StartDigestScheduler(m)
+	}
+	go jobs.StartTrashRetention()
+	go jobs.StartRankRebalancer()
+	go jobs.StartWebhookRetrier()
+	go jobs.StartDueDateTrigger()
+
 	router.Run(":8080")
 }
@@ -0,0 +1,118 @@
+// Package mentions finds @username mentions in card descriptions and comments
+// and keeps track of who is mentioned where.
+package mentions
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"trello-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// pattern matches an @ that starts a word, so email addresses are not mentions
+var pattern = regexp.MustCompile(`(?:^|[^\w@.])@(\w[\w.-]*)`)
+
+// Usernames returns the usernames mentioned in text, each once, in order
+func Usernames(text string) []string {
+	var names []string
+	for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+		// A mention can end a sentence: "thanks @alice."
+		name := strings.TrimRight(match[1], ".-")
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Users returns the users mentioned in text. Names that don't belong to a user
+// are left as plain text.
+func Users(tx *gorm.DB, text string) ([]models.User, error) {
+	names := Usernames(text)
+	if len(names) == 0 {
+		return nil, nil
+	}
+	var users []models.User
+	err := tx.Where("username IN ?", names).Find(&users).Error
+	return users, err
+}
+
+// Sync makes the mentions stored for a card's description (commentID nil) or
+// one of its comments match users, and returns the users who are newly
+// mentioned. Users who stay mentioned are not returned again.
+func Sync(tx *gorm.DB, cardID string, commentID *string, users []models.User) ([]models.User, error) {
+	query := tx.Where("card_id = ?", cardID)
+	if commentID == nil {
+		query = query.Where("comment_id IS NULL")
+	} else {
+		query = query.Where("comment_id = ?", *commentID)
+	}
+
+	var existing []models.Mention
+	if err := query.Find(&existing).Error; err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	for _, mention := range existing {
+		if !slices.ContainsFunc(users, func(u models.User) bool { return u.ID == mention.UserID }) {
+			removed = append(removed, mention.ID)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Where("id IN ?", removed).Delete(&models.Mention{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var added []models.User
+	for _, user := range users {
+		if slices.ContainsFunc(existing, func(m models.Mention) bool { return m.UserID == user.ID }) {
+			continue
+		}
+		mention := models.Mention{ID: uuid.NewString(), CardID: cardID, CommentID: commentID, UserID: user.ID}
+		if err := tx.Omit("Card", "Comment", "User").Create(&mention).Error; err != nil {
+			return nil, err
+		}
+		added = append(added, user)
+	}
+	return added, nil
+}
+
+// InDescriptions returns who is mentioned in the descriptions of the given
+// cards, by card ID. cardIDs is a slice of IDs or a subquery selecting them.
+func InDescriptions(tx *gorm.DB, cardIDs interface{}) (map[string][]models.UserSummary, error) {
+	var found []models.Mention
+	if err := tx.Preload("User").Where("card_id IN (?) AND comment_id IS NULL", cardIDs).
+		Order("created_at, id").Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byCard := make(map[string][]models.UserSummary)
+	for _, mention := range found {
+		byCard[mention.CardID] = append(byCard[mention.CardID], summary(mention.User))
+	}
+	return byCard, nil
+}
+
+// InComments returns who is mentioned in each comment of a card, by comment ID
+func InComments(tx *gorm.DB, cardID string) (map[string][]models.UserSummary, error) {
+	var found []models.Mention
+	if err := tx.Preload("User").Where("card_id = ? AND comment_id IS NOT NULL", cardID).
+		Order("created_at, id").Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	byComment := make(map[string][]models.UserSummary)
+	for _, mention := range found {
+		byComment[*mention.CommentID] = append(byComment[*mention.CommentID], summary(mention.User))
+	}
+	return byComment, nil
+}
+
+func summary(user models.User) models.UserSummary {
+	return models.UserSummary{ID: user.ID, Username: user.Username}
+}
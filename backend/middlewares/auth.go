@@ -1,6 +1,7 @@
 package middlewares
 
 import (
+	"errors"
 	"log"
 	"net/http"
 	"os"
@@ -28,34 +29,70 @@ func InitJWTSecret() {
 	JwtSecret = []byte(secret)
 }
 
+var (
+	errMissingToken  = errors.New("no token in request")
+	errInvalidToken  = errors.New("invalid token")
+	errInvalidClaims = errors.New("invalid token claims")
+)
+
+// ParseToken validates a JWT and returns the ID of the user it was issued to
+func ParseToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return JwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return "", errInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", errInvalidClaims
+	}
+	userID, ok := claims["userID"].(string)
+	if !ok {
+		return "", errInvalidClaims
+	}
+	return userID, nil
+}
+
+// requestToken returns the bearer token of a request. Browsers can't set headers
+// on WebSocket handshakes or EventSource requests, so those may pass it in the
+// token query parameter instead.
+func requestToken(c *gin.Context) (string, error) {
+	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+		return strings.TrimPrefix(authHeader, "Bearer "), nil
+	}
+
+	isStream := strings.EqualFold(c.GetHeader("Upgrade"), "websocket") ||
+		strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	if token := c.Query("token"); isStream && token != "" {
+		return token, nil
+	}
+	return "", errMissingToken
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		tokenString, err := requestToken(c)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
 			c.Abort()
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return JwtSecret, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		userID, err := ParseToken(tokenString)
+		if errors.Is(err, errInvalidClaims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
 			c.Abort()
 			return
 		}
-
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		c.Set("userID", claims["userID"])
+		c.Set("userID", userID)
 		c.Next()
 	}
 }
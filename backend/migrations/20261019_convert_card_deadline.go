@@ -0,0 +1,63 @@
+package migrations
+
+import (
+	"log"
+	"time"
+	"trello-backend/config"
+)
+
+// legacyDeadlineLayouts covers the formats the old free-form deadline column held
+var legacyDeadlineLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02Z",
+	"2006-01-02",
+}
+
+// ConvertCardDeadline moves the legacy string deadline into the typed due_date
+// column. The old column is kept as legacy_deadline rather than dropped, so
+// values that cannot be parsed are logged and can still be fixed by hand.
+func ConvertCardDeadline() {
+	migrator := config.DB.Migrator()
+	if !migrator.HasColumn("cards", "deadline") {
+		return
+	}
+
+	var rows []struct {
+		ID       string
+		Deadline string
+	}
+	if err := config.DB.Table("cards").Select("id, deadline").
+		Where("deadline IS NOT NULL AND deadline <> ''").Scan(&rows).Error; err != nil {
+		log.Fatalf("Failed to read card deadlines: %v", err)
+	}
+
+	unparsed := 0
+	for _, row := range rows {
+		var dueDate time.Time
+		var err error
+		for _, layout := range legacyDeadlineLayouts {
+			if dueDate, err = time.Parse(layout, row.Deadline); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			log.Printf("Skipping unparseable deadline %q on card %s", row.Deadline, row.ID)
+			unparsed++
+			continue
+		}
+
+		if err := config.DB.Table("cards").Where("id = ? AND due_date IS NULL", row.ID).
+			Update("due_date", dueDate.UTC()).Error; err != nil {
+			log.Fatalf("Failed to convert deadline for card %s: %v", row.ID, err)
+		}
+	}
+
+	if err := migrator.RenameColumn("cards", "deadline", "legacy_deadline"); err != nil {
+		log.Fatalf("Failed to rename card deadline column: %v", err)
+	}
+	if unparsed > 0 {
+		log.Printf("%d card deadlines could not be converted; their original values are in cards.legacy_deadline", unparsed)
+	}
+}
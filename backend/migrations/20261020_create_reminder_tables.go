@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateReminderTables() {
+	err := config.DB.AutoMigrate(&models.User{}, &models.CardReminder{}, &models.Notification{})
+	if err != nil {
+		log.Fatalf("Failed to migrate reminder tables: %v", err)
+	}
+}
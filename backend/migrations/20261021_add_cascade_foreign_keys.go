@@ -0,0 +1,67 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+)
+
+type foreignKey struct {
+	Table      string
+	Name       string
+	Column     string
+	References string
+}
+
+// cascadeForeignKeys use the constraint names GORM generates for the model relations
+var cascadeForeignKeys = []foreignKey{
+	{"lists", "fk_lists_board", "board_id", "boards"},
+	{"cards", "fk_cards_list", "list_id", "lists"},
+	{"board_members", "fk_board_members_board", "board_id", "boards"},
+	{"board_members", "fk_board_members_user", "user_id", "users"},
+	{"card_assignees", "fk_card_assignees_card", "card_id", "cards"},
+	{"card_assignees", "fk_card_assignees_user", "user_id", "users"},
+}
+
+// AddCascadeForeignKeys replaces the existing foreign keys with ON DELETE CASCADE ones.
+// They are added NOT VALID so rows orphaned before this migration don't block startup;
+// run cmd/cleanup-orphans to remove those rows and validate the constraints.
+// It runs before the table migrations so AutoMigrate finds the constraints in place.
+func AddCascadeForeignKeys() {
+	migrator := config.DB.Migrator()
+	for _, fk := range cascadeForeignKeys {
+		if !migrator.HasTable(fk.Table) || !migrator.HasTable(fk.References) {
+			continue
+		}
+
+		var cascades bool
+		if err := config.DB.Raw("SELECT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = ? AND confdeltype = 'c')",
+			fk.Name).Scan(&cascades).Error; err != nil {
+			log.Fatalf("Failed to inspect constraint %s: %v", fk.Name, err)
+		}
+		if cascades {
+			continue
+		}
+
+		if err := config.DB.Exec("ALTER TABLE " + fk.Table + " DROP CONSTRAINT IF EXISTS " + fk.Name).Error; err != nil {
+			log.Fatalf("Failed to drop constraint %s: %v", fk.Name, err)
+		}
+		if err := config.DB.Exec("ALTER TABLE " + fk.Table + " ADD CONSTRAINT " + fk.Name +
+			" FOREIGN KEY (" + fk.Column + ") REFERENCES " + fk.References + "(id) ON DELETE CASCADE NOT VALID").Error; err != nil {
+			log.Fatalf("Failed to add constraint %s: %v", fk.Name, err)
+		}
+	}
+}
+
+// ValidateCascadeForeignKeys checks existing rows against the cascading foreign keys.
+// It fails while orphaned rows remain.
+func ValidateCascadeForeignKeys() error {
+	for _, fk := range cascadeForeignKeys {
+		if !config.DB.Migrator().HasConstraint(fk.Table, fk.Name) {
+			continue
+		}
+		if err := config.DB.Exec("ALTER TABLE " + fk.Table + " VALIDATE CONSTRAINT " + fk.Name).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/rank"
+
+	"gorm.io/gorm"
+)
+
+// ConvertPositionsToRanks replaces the integer positions of lists and cards with
+// rank keys, keeping the current order, and drops the position columns.
+func ConvertPositionsToRanks() {
+	convertPositions("lists", "board_id")
+	convertPositions("cards", "list_id")
+}
+
+func convertPositions(table, parentColumn string) {
+	migrator := config.DB.Migrator()
+	if !migrator.HasColumn(table, "position") {
+		return
+	}
+
+	var rows []struct {
+		ID     string
+		Parent string
+	}
+	if err := config.DB.Table(table).Select("id, COALESCE(" + parentColumn + ", '') AS parent").
+		Order(parentColumn + ", position, id").Scan(&rows).Error; err != nil {
+		log.Fatalf("Failed to read %s positions: %v", table, err)
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(rows); {
+			end := start
+			for end < len(rows) && rows[end].Parent == rows[start].Parent {
+				end++
+			}
+
+			keys := rank.Spread(end - start)
+			for i, row := range rows[start:end] {
+				if err := tx.Table(table).Where("id = ?", row.ID).Update("rank", keys[i]).Error; err != nil {
+					return err
+				}
+			}
+			start = end
+		}
+		return tx.Migrator().DropColumn(table, "position")
+	})
+	if err != nil {
+		log.Fatalf("Failed to convert %s positions to ranks: %v", table, err)
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateLabelTable() {
+	err := config.DB.AutoMigrate(&models.Label{})
+	if err != nil {
+		log.Fatalf("Failed to migrate label table: %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/rank"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type templateList struct {
+	name  string
+	cards []string
+}
+
+type builtInTemplate struct {
+	id     string
+	name   string
+	labels []string
+	lists  []templateList
+}
+
+// builtInTemplates are available to every user. Their IDs are fixed so seeding
+// can tell which ones already exist.
+var builtInTemplates = []builtInTemplate{
+	{
+		id:   "template-kanban",
+		name: "Kanban",
+		lists: []templateList{
+			{name: "To Do"},
+			{name: "Doing"},
+			{name: "Done"},
+		},
+	},
+	{
+		id:     "template-scrum-sprint",
+		name:   "Sprint {{sprint}}",
+		labels: []string{"Story", "Task", "Bug"},
+		lists: []templateList{
+			{name: "Sprint Backlog", cards: []string{"Sprint {{sprint}} planning ({{date}})"}},
+			{name: "In Progress"},
+			{name: "Review"},
+			{name: "Done", cards: []string{"Sprint {{sprint}} review", "Sprint {{sprint}} retrospective"}},
+		},
+	},
+	{
+		id:     "template-bug-triage",
+		name:   "Bug triage",
+		labels: []string{"Critical", "Major", "Minor"},
+		lists: []templateList{
+			{name: "New", cards: []string{"How to report a bug"}},
+			{name: "Triaged"},
+			{name: "In Progress"},
+			{name: "Fixed"},
+			{name: "Won't Fix"},
+		},
+	},
+}
+
+// SeedBoardTemplates creates the built-in board templates that don't exist yet
+func SeedBoardTemplates() {
+	for _, template := range builtInTemplates {
+		var count int64
+		if err := config.DB.Unscoped().Model(&models.Board{}).Where("id = ?", template.id).Count(&count).Error; err != nil {
+			log.Fatalf("Failed to check board templates: %v", err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		if err := config.DB.Transaction(func(tx *gorm.DB) error {
+			return seedBoardTemplate(tx, template)
+		}); err != nil {
+			log.Fatalf("Failed to seed board template %s: %v", template.id, err)
+		}
+	}
+}
+
+func seedBoardTemplate(tx *gorm.DB, template builtInTemplate) error {
+	board := models.Board{
+		ID:         template.id,
+		Name:       template.name,
+		IsTemplate: true,
+		BuiltIn:    true,
+	}
+	if err := tx.Create(&board).Error; err != nil {
+		return err
+	}
+
+	for _, name := range template.labels {
+		if err := tx.Create(&models.Label{ID: uuid.NewString(), BoardID: board.ID, Name: name}).Error; err != nil {
+			return err
+		}
+	}
+
+	listRanks := rank.Spread(len(template.lists))
+	for i, templateList := range template.lists {
+		list := models.List{ID: uuid.NewString(), Name: templateList.name, Rank: listRanks[i], BoardID: board.ID}
+		if err := tx.Create(&list).Error; err != nil {
+			return err
+		}
+
+		cardRanks := rank.Spread(len(templateList.cards))
+		for j, title := range templateList.cards {
+			card := models.Card{ID: uuid.NewString(), Title: title, Rank: cardRanks[j], ListID: list.ID}
+			if err := tx.Create(&card).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
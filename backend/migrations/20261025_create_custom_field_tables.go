@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateCustomFieldTables() {
+	err := config.DB.AutoMigrate(&models.CustomField{}, &models.CustomFieldValue{})
+	if err != nil {
+		log.Fatalf("Failed to migrate custom field tables: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateCardDependencyTable() {
+	err := config.DB.AutoMigrate(&models.CardDependency{})
+	if err != nil {
+		log.Fatalf("Failed to migrate card dependency table: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateSubscriptionTables() {
+	err := config.DB.AutoMigrate(&models.Subscription{}, &models.Comment{})
+	if err != nil {
+		log.Fatalf("Failed to migrate subscription tables: %v", err)
+	}
+}
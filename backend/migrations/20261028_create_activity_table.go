@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateActivityTable() {
+	err := config.DB.AutoMigrate(&models.Activity{})
+	if err != nil {
+		log.Fatalf("Failed to migrate activity table: %v", err)
+	}
+
+	// The log used to cascade away with its board when the trash was purged
+	if err := config.DB.Exec("ALTER TABLE activities DROP CONSTRAINT IF EXISTS fk_activities_board").Error; err != nil {
+		log.Fatalf("Failed to drop constraint fk_activities_board: %v", err)
+	}
+}
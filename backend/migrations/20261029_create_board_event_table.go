@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateBoardEventTable() {
+	err := config.DB.AutoMigrate(&models.BoardEvent{})
+	if err != nil {
+		log.Fatalf("Failed to migrate board event table: %v", err)
+	}
+}
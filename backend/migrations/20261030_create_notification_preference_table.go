@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateNotificationPreferenceTable() {
+	err := config.DB.AutoMigrate(&models.NotificationPreference{})
+	if err != nil {
+		log.Fatalf("Failed to migrate notification preference table: %v", err)
+	}
+}
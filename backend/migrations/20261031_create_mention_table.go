@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateMentionTable() {
+	err := config.DB.AutoMigrate(&models.Mention{})
+	if err != nil {
+		log.Fatalf("Failed to migrate mention table: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateEmailDigestTables() {
+	err := config.DB.AutoMigrate(&models.User{}, &models.DigestEntry{})
+	if err != nil {
+		log.Fatalf("Failed to migrate email digest tables: %v", err)
+	}
+}
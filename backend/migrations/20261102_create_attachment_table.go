@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateAttachmentTable() {
+	err := config.DB.AutoMigrate(&models.Attachment{})
+	if err != nil {
+		log.Fatalf("Failed to migrate attachment table: %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateWebhookTables() {
+	err := config.DB.AutoMigrate(&models.Webhook{}, &models.WebhookDelivery{}, &models.WebhookAttempt{})
+	if err != nil {
+		log.Fatalf("Failed to migrate webhook tables: %v", err)
+	}
+
+	// Attempts used to keep the start of the response body, which could echo
+	// back whatever internal service a webhook pointed at
+	migrator := config.DB.Migrator()
+	if migrator.HasColumn(&models.WebhookAttempt{}, "response_body") {
+		if err := migrator.DropColumn(&models.WebhookAttempt{}, "response_body"); err != nil {
+			log.Fatalf("Failed to drop webhook_attempts.response_body: %v", err)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateAutomationTables() {
+	err := config.DB.AutoMigrate(&models.AutomationRule{}, &models.AutomationRun{}, &models.CardDueTrigger{})
+	if err != nil {
+		log.Fatalf("Failed to migrate automation tables: %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+func CreateCollabTables() {
+	err := config.DB.AutoMigrate(&models.CollabPresence{}, &models.CollabLock{})
+	if err != nil {
+		log.Fatalf("Failed to migrate collaboration tables: %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package migrations
+
+import (
+	"log"
+	"trello-backend/config"
+)
+
+// RenameWIPColumns renames the WIP columns from the names GORM first derived for
+// them, w_ip_mode and w_ip_limit, to the wip_mode and wip_limit the code uses. It
+// runs before the table migrations, which would otherwise add them as new columns.
+func RenameWIPColumns() {
+	migrator := config.DB.Migrator()
+	for _, column := range []struct{ table, from, to string }{
+		{"boards", "w_ip_mode", "wip_mode"},
+		{"lists", "w_ip_limit", "wip_limit"},
+	} {
+		if !migrator.HasColumn(column.table, column.from) || migrator.HasColumn(column.table, column.to) {
+			continue
+		}
+		if err := migrator.RenameColumn(column.table, column.from, column.to); err != nil {
+			log.Fatalf("Failed to rename %s.%s: %v", column.table, column.from, err)
+		}
+	}
+}
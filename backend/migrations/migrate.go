@@ -0,0 +1,28 @@
+package migrations
+
+// Migrate runs every migration in order. Each step is safe to run again.
+func Migrate() {
+	AddCascadeForeignKeys()
+	RenameWIPColumns()
+	CreateBoardTable()
+	// Labels go before cards, which reference them through card_labels
+	CreateLabelTable()
+	CreateListTable()
+	CreateCardTable()
+	ConvertCardDeadline()
+	CreateReminderTables()
+	ConvertPositionsToRanks()
+	SeedBoardTemplates()
+	CreateCustomFieldTables()
+	CreateCardDependencyTable()
+	CreateSubscriptionTables()
+	CreateActivityTable()
+	CreateBoardEventTable()
+	CreateNotificationPreferenceTable()
+	CreateMentionTable()
+	CreateEmailDigestTables()
+	CreateAttachmentTable()
+	CreateWebhookTables()
+	CreateAutomationTables()
+	CreateCollabTables()
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Activity is an append-only record of a change made on a board. Before and
+// After hold only the fields that changed, or the whole entity when it was
+// created or deleted. BoardID has no foreign key, so the record outlives a
+// board purged from the trash.
+type Activity struct {
+	ID         string                 `gorm:"primaryKey"`
+	BoardID    string                 `gorm:"not null;index:idx_activity_board"`
+	ActorID    string                 `gorm:"not null"`
+	Actor      User                   `gorm:"foreignKey:ActorID"`
+	Action     string                 `gorm:"not null"`
+	EntityType string                 `gorm:"not null;index:idx_activity_entity"`
+	EntityID   string                 `gorm:"not null;index:idx_activity_entity"`
+	Before     map[string]interface{} `gorm:"serializer:json"`
+	After      map[string]interface{} `gorm:"serializer:json"`
+	CreatedAt  time.Time              `gorm:"index"`
+}
+
+type ActivityResponse struct {
+	ID         string                 `json:"id"`
+	BoardID    string                 `json:"boardId"`
+	Actor      UserSummary            `json:"actor"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entityType"`
+	EntityID   string                 `json:"entityId"`
+	Before     map[string]interface{} `json:"before"`
+	After      map[string]interface{} `json:"after"`
+	CreatedAt  time.Time              `json:"createdAt"`
+}
+
+// ActivityPage is one page of an activity feed, newest first. NextCursor is
+// passed as "before" to get the next page and is empty on the last one.
+type ActivityPage struct {
+	Activities []ActivityResponse `json:"activities"`
+	NextCursor string             `json:"nextCursor"`
+}
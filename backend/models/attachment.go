@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Attachment is a file kept on a card
+type Attachment struct {
+	ID          string `gorm:"primaryKey"`
+	CardID      string `gorm:"not null;index"`
+	Card        Card   `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE;"`
+	UploaderID  string `gorm:"not null"`
+	Uploader    User   `gorm:"foreignKey:UploaderID;constraint:OnDelete:CASCADE;"`
+	Filename    string `gorm:"not null"`
+	ContentType string `gorm:"not null"`
+	Size        int    `gorm:"not null"`
+	Data        []byte `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+type AttachmentResponse struct {
+	ID          string      `json:"id"`
+	CardID      string      `json:"cardId"`
+	Uploader    UserSummary `json:"uploader"`
+	Filename    string      `json:"filename"`
+	ContentType string      `json:"contentType"`
+	Size        int         `json:"size"`
+	CreatedAt   time.Time   `json:"createdAt"`
+}
@@ -0,0 +1,139 @@
+package models
+
+import "time"
+
+// Rule triggers, named after the board events that start them
+const (
+	TriggerCardCreated = "card.created"
+	TriggerCardMoved   = "card.moved"
+	TriggerDueDate     = "card.due"
+	TriggerLabelAdded  = "label.added"
+)
+
+// Rule conditions, each checked against the card
+const (
+	ConditionLabel    = "label"
+	ConditionList     = "list"
+	ConditionAssignee = "assignee"
+)
+
+// Rule actions
+const (
+	RuleActionMove        = "move"
+	RuleActionAssign      = "assign"
+	RuleActionUnassign    = "unassign"
+	RuleActionAddLabel    = "add_label"
+	RuleActionComment     = "comment"
+	RuleActionArchive     = "archive"
+	RuleActionCompleteDue = "complete_due"
+)
+
+// AutomationRule runs its actions on a card when its trigger happens and all
+// of its conditions hold. The changes are made as the rule's creator.
+type AutomationRule struct {
+	ID         string          `gorm:"primaryKey"`
+	BoardID    string          `gorm:"not null;index"`
+	Board      Board           `gorm:"foreignKey:BoardID;constraint:OnDelete:CASCADE;"`
+	CreatorID  string          `gorm:"not null"`
+	Creator    User            `gorm:"foreignKey:CreatorID;constraint:OnDelete:CASCADE;"`
+	Name       string          `gorm:"not null"`
+	Trigger    RuleTrigger     `gorm:"serializer:json;not null"`
+	Conditions []RuleCondition `gorm:"serializer:json"`
+	Actions    []RuleAction    `gorm:"serializer:json;not null"`
+	Enabled    bool            `gorm:"not null"`
+	// DryRun rules log what they would do instead of doing it
+	DryRun    bool `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// RuleTrigger is the event that starts a rule. ListID narrows card triggers to
+// cards in that list, e.g. moved into "Done"; LabelID narrows label.added to one label.
+type RuleTrigger struct {
+	Type    string `json:"type"`
+	ListID  string `json:"listId,omitempty"`
+	LabelID string `json:"labelId,omitempty"`
+}
+
+// RuleCondition checks that the card has a label, is in a list or is assigned
+// to a user; Not turns it around
+type RuleCondition struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+	Not  bool   `json:"not,omitempty"`
+}
+
+// RuleAction is one change a rule makes. Only the fields its type uses are set;
+// unassign without a UserID removes every assignee.
+type RuleAction struct {
+	Type    string `json:"type"`
+	ListID  string `json:"listId,omitempty"`
+	UserID  string `json:"userId,omitempty"`
+	LabelID string `json:"labelId,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+// Outcomes of a rule run
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+	RunDryRun    = "dry_run"
+	// RunLoopPrevented runs were stopped because the rule already ran earlier in
+	// the chain of events, or the chain grew too long
+	RunLoopPrevented = "loop_prevented"
+)
+
+// AutomationRun is an entry in the execution log: a rule that matched an event
+type AutomationRun struct {
+	ID        string         `gorm:"primaryKey"`
+	RuleID    string         `gorm:"not null;index"`
+	Rule      AutomationRule `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE;"`
+	BoardID   string         `gorm:"not null;index"`
+	CardID    string         `gorm:"not null"`
+	EventType string         `gorm:"not null"`
+	Status    string         `gorm:"not null"`
+	// Actions describes what the rule did, or would have done in a dry run
+	Actions   []string `gorm:"serializer:json"`
+	Error     string
+	CreatedAt time.Time
+}
+
+// CardDueTrigger records that a card's due date has fired the due-date
+// trigger, so it fires once per due date
+type CardDueTrigger struct {
+	CardID      string    `gorm:"primaryKey"`
+	Card        Card      `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE;"`
+	DueDate     time.Time `gorm:"primaryKey"`
+	TriggeredAt time.Time `gorm:"not null"`
+}
+
+type AutomationRuleResponse struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Trigger    RuleTrigger     `json:"trigger"`
+	Conditions []RuleCondition `json:"conditions"`
+	Actions    []RuleAction    `json:"actions"`
+	Enabled    bool            `json:"enabled"`
+	DryRun     bool            `json:"dryRun"`
+	Creator    UserSummary     `json:"creator"`
+	CreatedAt  time.Time       `json:"createdAt"`
+	UpdatedAt  time.Time       `json:"updatedAt"`
+}
+
+type AutomationRunResponse struct {
+	ID        string    `json:"id"`
+	RuleID    string    `json:"ruleId"`
+	RuleName  string    `json:"ruleName"`
+	CardID    string    `json:"cardId"`
+	EventType string    `json:"eventType"`
+	Status    string    `json:"status"`
+	Actions   []string  `json:"actions"`
+	Error     string    `json:"error"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AutomationRunPage is one page of a board's execution log, newest first
+type AutomationRunPage struct {
+	Runs       []AutomationRunResponse `json:"runs"`
+	NextCursor string                  `json:"nextCursor"`
+}
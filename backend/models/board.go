@@ -1,22 +1,83 @@
 package models
 
+import "time"
+
 type CardResponse struct {
-	ID          string `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Position    int    `json:"position"`
-	Deadline    string `json:"deadline"`
+	ID           string                     `json:"id"`
+	Title        string                     `json:"title"`
+	Description  string                     `json:"description"`
+	Position     int                        `json:"position"`
+	Rank         string                     `json:"rank"`
+	StartDate    *time.Time                 `json:"startDate"`
+	DueDate      *time.Time                 `json:"dueDate"`
+	DueComplete  bool                       `json:"dueComplete"`
+	Overdue      bool                       `json:"overdue"`
+	DueSoon      bool                       `json:"dueSoon"`
+	Archived     bool                       `json:"archived"`
+	Version      int                        `json:"version"`
+	Labels       []LabelResponse            `json:"labels"`
+	IsTemplate   bool                       `json:"isTemplate"`
+	CustomFields []CustomFieldValueResponse `json:"customFields"`
+	Blocks       []string                   `json:"blocks"`    // IDs of the cards this card blocks
+	BlockedBy    []string                   `json:"blockedBy"` // IDs of the cards blocking this card
+	Watching     bool                       `json:"watching"`  // whether the current user watches the card
+	Mentions     []UserSummary              `json:"mentions"`  // users mentioned in the description
 }
 
 type ListResponse struct {
-	ID       string         `json:"id"`
-	Name     string         `json:"name"`
-	Position int            `json:"position"`
-	Cards    []CardResponse `json:"cards"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Rank     string `json:"rank"`
+	Archived bool   `json:"archived"`
+	IsDone   bool   `json:"isDone"`
+	// CardCount is the number of unarchived cards, to compare against WIPLimit
+	CardCount int            `json:"cardCount"`
+	WIPLimit  *int           `json:"wipLimit"`
+	Cards     []CardResponse `json:"cards"`
+}
+
+// BoardSummary is a board without its members, lists or owner, as published in
+// board.updated events
+type BoardSummary struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	IsTemplate        bool   `json:"isTemplate"`
+	BlockedMovePolicy string `json:"blockedMovePolicy"`
+	WIPMode           string `json:"wipMode"`
 }
 
 type BoardFullResponse struct {
-	ID    string         `json:"id"`
-	Name  string         `json:"name"`
-	Lists []ListResponse `json:"lists"`
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	Archived   bool   `json:"archived"`
+	IsTemplate bool   `json:"isTemplate"`
+	// BlockedMovePolicy is "warn" or "error"
+	BlockedMovePolicy string `json:"blockedMovePolicy"`
+	// WIPMode is "soft" or "hard"
+	WIPMode string         `json:"wipMode"`
+	Lists   []ListResponse `json:"lists"`
+}
+
+// TrashResponse lists the soft-deleted lists and cards of a board
+type TrashResponse struct {
+	Lists []TrashItem `json:"lists"`
+	Cards []TrashItem `json:"cards"`
+}
+
+type TrashItem struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	ListID    string    `json:"listId,omitempty"`
+	DeletedAt time.Time `json:"deletedAt"`
+	PurgeAt   time.Time `json:"purgeAt"`
+}
+
+// CopyResponse holds the ID of a copy and the IDs of what was copied along with
+// it, keyed by the ID of the original
+type CopyResponse struct {
+	ID       string            `json:"id"`
+	ListIDs  map[string]string `json:"listIds,omitempty"`
+	CardIDs  map[string]string `json:"cardIds,omitempty"`
+	LabelIDs map[string]string `json:"labelIds,omitempty"`
 }
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// CollabPresence is a socket on a board's collaboration channel, with the card
+// it has open and the field it is typing in. The instance holding the socket
+// renews it, so it expires if that instance goes away.
+type CollabPresence struct {
+	ClientID     string `gorm:"primaryKey"`
+	BoardID      string `gorm:"not null;index"`
+	UserID       string `gorm:"not null"`
+	Username     string `gorm:"not null"`
+	CardID       string
+	TypingCardID string
+	TypingField  string
+	TypingUntil  *time.Time
+	ExpiresAt    time.Time `gorm:"not null;index"`
+}
+
+// CollabLock is a soft lock a user holds on a field of a card, taken through
+// one of their sockets. It lasts until ExpiresAt unless renewed.
+type CollabLock struct {
+	BoardID   string    `gorm:"primaryKey"`
+	CardID    string    `gorm:"primaryKey"`
+	Field     string    `gorm:"primaryKey"`
+	ClientID  string    `gorm:"not null;index"`
+	UserID    string    `gorm:"not null"`
+	Username  string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
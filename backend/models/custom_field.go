@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Custom field types
+const (
+	FieldTypeText     = "text"
+	FieldTypeNumber   = "number"
+	FieldTypeDate     = "date"
+	FieldTypeCheckbox = "checkbox"
+	FieldTypeDropdown = "dropdown"
+)
+
+// CustomField is a piece of metadata a board tracks on its cards
+type CustomField struct {
+	ID      string `gorm:"primaryKey"`
+	BoardID string `gorm:"not null;index"`
+	Board   Board  `gorm:"foreignKey:BoardID;constraint:OnDelete:CASCADE;"`
+	Name    string `gorm:"not null"`
+	Type    string `gorm:"not null"`
+	// Options are the choices of a dropdown field
+	Options []string `gorm:"serializer:json"`
+}
+
+// CustomFieldValue is a card's value for a custom field. Only the column
+// matching the field's type is set, so values sort and filter by type.
+type CustomFieldValue struct {
+	CardID       string      `gorm:"primaryKey"`
+	Card         Card        `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE;"`
+	FieldID      string      `gorm:"primaryKey;index"`
+	Field        CustomField `gorm:"foreignKey:FieldID;constraint:OnDelete:CASCADE;"`
+	TextValue    *string
+	NumberValue  *float64
+	DateValue    *time.Time
+	CheckedValue *bool
+}
+
+type CustomFieldResponse struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options"`
+}
+
+type CustomFieldValueResponse struct {
+	FieldID string      `json:"fieldId"`
+	Name    string      `json:"name"`
+	Type    string      `json:"type"`
+	Value   interface{} `json:"value"`
+}
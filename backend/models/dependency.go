@@ -0,0 +1,15 @@
+package models
+
+// Policies for moving a blocked card into a done list
+const (
+	BlockedMoveWarn  = "warn"
+	BlockedMoveError = "error"
+)
+
+// CardDependency records that one card blocks another on the same board
+type CardDependency struct {
+	BlockingCardID string `gorm:"primaryKey"`
+	BlockingCard   Card   `gorm:"foreignKey:BlockingCardID;constraint:OnDelete:CASCADE;"`
+	BlockedCardID  string `gorm:"primaryKey;index"`
+	BlockedCard    Card   `gorm:"foreignKey:BlockedCardID;constraint:OnDelete:CASCADE;"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// BoardEvent keeps a published board event for a while, so instances that lost
+// their listening connection can catch up, and events too large for a NOTIFY
+// payload can be read by ID
+type BoardEvent struct {
+	ID        int64     `gorm:"primaryKey;autoIncrement"`
+	BoardID   string    `gorm:"not null"`
+	Payload   string    `gorm:"type:jsonb;not null"`
+	CreatedAt time.Time `gorm:"not null;index"`
+}
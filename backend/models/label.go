@@ -0,0 +1,25 @@
+package models
+
+// EntityLabel is the entity type of label changes in the activity log.
+// Labels can't be watched.
+const EntityLabel = "label"
+
+// Label is a coloured tag defined per board and attached to that board's cards
+type Label struct {
+	ID      string `gorm:"primaryKey"`
+	BoardID string `gorm:"not null;index"`
+	Board   Board  `gorm:"foreignKey:BoardID;constraint:OnDelete:CASCADE;"`
+	Name    string `gorm:"not null"`
+	Color   string
+}
+
+type CardLabel struct {
+	CardID  string `gorm:"primaryKey"`
+	LabelID string `gorm:"primaryKey"`
+}
+
+type LabelResponse struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
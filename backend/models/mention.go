@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// Mention records that a card's description or one of its comments mentions a user
+type Mention struct {
+	ID     string `gorm:"primaryKey"`
+	CardID string `gorm:"not null;index"`
+	Card   Card   `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE;"`
+	// CommentID is set for mentions in a comment and nil for the card description
+	CommentID *string
+	Comment   Comment `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE;"`
+	UserID    string  `gorm:"not null"`
+	User      User    `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	CreatedAt time.Time
+}
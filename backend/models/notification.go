@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// Notification is an in-app message delivered to a single user
+type Notification struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string `gorm:"not null;index"`
+	Type      string `gorm:"not null"`
+	Title     string `gorm:"not null"`
+	Body      string
+	BoardID   string
+	CardID    string
+	ReadAt    *time.Time
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// CardReminder records that a due-date reminder went out, so each user is
+// reminded at most once per card and due date
+type CardReminder struct {
+	ID      string    `gorm:"primaryKey"`
+	CardID  string    `gorm:"not null;uniqueIndex:idx_card_reminder"`
+	UserID  string    `gorm:"not null;uniqueIndex:idx_card_reminder"`
+	DueDate time.Time `gorm:"not null;uniqueIndex:idx_card_reminder"`
+	SentAt  time.Time `gorm:"not null"`
+}
+
+// NotificationPreference turns a notification type on or off for one channel.
+// Types without a row are delivered on every channel.
+type NotificationPreference struct {
+	UserID string `gorm:"primaryKey"`
+	User   User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	Type   string `gorm:"primaryKey"`
+	InApp  bool   `gorm:"not null"`
+	Email  bool   `gorm:"not null"`
+}
+
+type NotificationResponse struct {
+	ID        string     `json:"id"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	BoardID   string     `json:"boardId,omitempty"`
+	CardID    string     `json:"cardId,omitempty"`
+	Read      bool       `json:"read"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// NotificationPage is one page of a user's inbox, newest first. NextCursor is
+// passed as "before" to get the next page and is empty on the last one.
+type NotificationPage struct {
+	Notifications []NotificationResponse `json:"notifications"`
+	NextCursor    string                 `json:"nextCursor"`
+}
+
+type NotificationPreferenceResponse struct {
+	Type  string `json:"type"`
+	InApp bool   `json:"inApp"`
+	Email bool   `json:"email"`
+}
+
+// DigestEntry is a notification waiting for the user's next email digest
+type DigestEntry struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string `gorm:"not null;index"`
+	User      User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	Type      string `gorm:"not null"`
+	Title     string `gorm:"not null"`
+	Body      string
+	BoardID   string
+	CardID    string
+	CreatedAt time.Time
+}
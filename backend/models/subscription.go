@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Kinds of things a user can watch
+const (
+	EntityBoard = "board"
+	EntityList  = "list"
+	EntityCard  = "card"
+)
+
+// Subscription means a user is notified about changes to a board, list or card
+type Subscription struct {
+	UserID     string `gorm:"primaryKey"`
+	User       User   `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE;"`
+	EntityType string `gorm:"primaryKey;index:idx_subscription_entity"`
+	EntityID   string `gorm:"primaryKey;index:idx_subscription_entity"`
+	CreatedAt  time.Time
+}
+
+// Comment is a message left on a card
+type Comment struct {
+	ID        string `gorm:"primaryKey"`
+	CardID    string `gorm:"not null;index"`
+	Card      Card   `gorm:"foreignKey:CardID;constraint:OnDelete:CASCADE;"`
+	AuthorID  string `gorm:"not null"`
+	Author    User   `gorm:"foreignKey:AuthorID;constraint:OnDelete:CASCADE;"`
+	Body      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CommentResponse struct {
+	ID        string        `json:"id"`
+	CardID    string        `json:"cardId"`
+	Author    UserSummary   `json:"author"`
+	Body      string        `json:"body"`
+	Mentions  []UserSummary `json:"mentions"`
+	CreatedAt time.Time     `json:"createdAt"`
+	UpdatedAt time.Time     `json:"updatedAt"`
+}
@@ -1,11 +1,40 @@
 package models
 
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
 type User struct {
 	ID       string  `gorm:"primaryKey"`
 	Username string  `gorm:"unique;not null"`
 	Email    string  `gorm:"unique;not null"`
-	Password string  `gorm:"not null"`
-	Boards   []Board `gorm:"many2many:board_members;"`
+	Password string  `gorm:"not null" json:"-"`
+	Boards   []Board `gorm:"many2many:board_members;constraint:OnDelete:CASCADE;"`
+	// ReminderLeadMinutes is how long before a due date reminders go out; 0 disables them
+	ReminderLeadMinutes int `gorm:"not null;default:1440"`
+	// EmailDelivery is when notification emails go out: one of the EmailDelivery* values
+	EmailDelivery string `gorm:"not null;default:immediate"`
+	LastDigestAt  *time.Time
+}
+
+// MaxReminderLeadMinutes caps reminder lead time at 30 days. The reminder
+// scheduler looks this far ahead for due cards.
+const MaxReminderLeadMinutes = 30 * 24 * 60
+
+// When a user's notification emails are sent
+const (
+	EmailDeliveryOff       = "off"
+	EmailDeliveryImmediate = "immediate"
+	EmailDeliveryDaily     = "daily"
+	EmailDeliveryWeekly    = "weekly"
+)
+
+// UserSummary identifies a user in responses without their private details
+type UserSummary struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
 }
 
 type BoardMember struct {
@@ -14,29 +43,82 @@ type BoardMember struct {
 }
 
 type Board struct {
-	ID      string `gorm:"primaryKey"`
-	Name    string `gorm:"not null"`
-	OwnerID string
-	Owner   User   `gorm:"foreignKey:OwnerID"`
-	Members []User `gorm:"many2many:board_members;"`
+	ID         string `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+	OwnerID    string `gorm:"default:null"` // empty for built-in templates
+	Owner      User   `gorm:"foreignKey:OwnerID"`
+	Members    []User `gorm:"many2many:board_members;constraint:OnDelete:CASCADE;"`
+	ArchivedAt *time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	IsTemplate bool           `gorm:"not null;default:false"`
+	// BuiltIn templates are seeded by migrations and visible to every user
+	BuiltIn bool `gorm:"not null;default:false"`
+	// BlockedMovePolicy decides whether moving a blocked card into a done list warns or fails
+	BlockedMovePolicy string `gorm:"not null;default:warn"`
+	// WIPMode decides whether adding a card to a full list is flagged (soft) or refused (hard)
+	WIPMode string `gorm:"column:wip_mode;not null;default:soft"`
 }
 
+// Ways a board enforces list WIP limits
+const (
+	WIPModeSoft = "soft"
+	WIPModeHard = "hard"
+)
+
 type List struct {
-	ID       string `gorm:"primaryKey"`
-	Name     string `gorm:"not null"`
-	Position int
-	BoardID  string
-	Board    Board `gorm:"foreignKey:BoardID"`
+	ID         string `gorm:"primaryKey"`
+	Name       string `gorm:"not null"`
+	Rank       string `gorm:"index"`
+	BoardID    string
+	Board      Board `gorm:"foreignKey:BoardID;constraint:OnDelete:CASCADE;"`
+	ArchivedAt *time.Time
+	DeletedAt  gorm.DeletedAt `gorm:"index"`
+	// IsDone marks a list whose cards count as finished
+	IsDone bool `gorm:"not null;default:false"`
+	// WIPLimit is the most cards the list should hold; nil means no limit
+	WIPLimit *int `gorm:"column:wip_limit"`
 }
 
 type Card struct {
 	ID          string `gorm:"primaryKey"`
 	Title       string `gorm:"not null"`
 	Description string
-	Position    int
+	Rank        string `gorm:"index"`
 	ListID      string
-	List        List   `gorm:"foreignKey:ListID"`
-	Deadline    string `gorm:"default:null"`
+	List        List `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE;"`
+	StartDate   *time.Time
+	DueDate     *time.Time `gorm:"index"`
+	DueComplete bool       `gorm:"not null;default:false"`
+	Assignees   []User     `gorm:"many2many:card_assignees;constraint:OnDelete:CASCADE;"`
+	Labels      []Label    `gorm:"many2many:card_labels;constraint:OnDelete:CASCADE;"`
+	ArchivedAt  *time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+	// Version increases on every change, for optimistic concurrency control
+	Version    int  `gorm:"not null;default:1"`
+	IsTemplate bool `gorm:"not null;default:false"`
+	// Warnings about the last change, returned to the client but not stored
+	Warnings []string `gorm:"-" json:",omitempty"`
+}
+
+type CardAssignee struct {
+	CardID string `gorm:"primaryKey"`
+	UserID string `gorm:"primaryKey"`
+}
+
+// DueSoonWindow is how far ahead of its due date a card is flagged as due soon
+const DueSoonWindow = 24 * time.Hour
+
+// IsOverdue reports whether an incomplete card is past its due date
+func (c Card) IsOverdue(now time.Time) bool {
+	return c.DueDate != nil && !c.DueComplete && now.After(*c.DueDate)
+}
+
+// IsDueSoon reports whether an incomplete card is due within DueSoonWindow
+func (c Card) IsDueSoon(now time.Time) bool {
+	if c.DueDate == nil || c.DueComplete || now.After(*c.DueDate) {
+		return false
+	}
+	return c.DueDate.Sub(now) <= DueSoonWindow
 }
 
 type Collaborator struct {
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook posts a board's events to a URL outside the app
+type Webhook struct {
+	ID        string `gorm:"primaryKey"`
+	BoardID   string `gorm:"not null;index"`
+	Board     Board  `gorm:"foreignKey:BoardID;constraint:OnDelete:CASCADE;"`
+	CreatorID string `gorm:"not null"`
+	Creator   User   `gorm:"foreignKey:CreatorID;constraint:OnDelete:CASCADE;"`
+	URL       string `gorm:"not null"`
+	// Secret signs each delivery so the receiver can tell it came from us
+	Secret string `gorm:"not null"`
+	// Events are the event types sent; empty means every type
+	Events    []string `gorm:"serializer:json"`
+	Active    bool     `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent to a webhook, retried until it succeeds
+// or runs out of attempts
+type WebhookDelivery struct {
+	ID        string  `gorm:"primaryKey"`
+	WebhookID string  `gorm:"not null;index"`
+	Webhook   Webhook `gorm:"foreignKey:WebhookID;constraint:OnDelete:CASCADE;"`
+	EventType string  `gorm:"not null"`
+	// Payload is the JSON body posted to the webhook
+	Payload  string `gorm:"not null"`
+	Status   string `gorm:"not null;index"`
+	Attempts int    `gorm:"not null"`
+	// NextAttemptAt is when a pending delivery is tried next
+	NextAttemptAt *time.Time `gorm:"index"`
+	// ResponseCode is the status of the last attempt; nil when it got no response
+	ResponseCode *int
+	Error        string
+	// ReplayOf is the delivery this one was replayed from
+	ReplayOf    *string
+	CreatedAt   time.Time
+	DeliveredAt *time.Time
+}
+
+// WebhookAttempt records one try at a delivery
+type WebhookAttempt struct {
+	ID           string          `gorm:"primaryKey"`
+	DeliveryID   string          `gorm:"not null;index"`
+	Delivery     WebhookDelivery `gorm:"foreignKey:DeliveryID;constraint:OnDelete:CASCADE;"`
+	ResponseCode *int
+	Error        string
+	DurationMs   int `gorm:"not null"`
+	CreatedAt    time.Time
+}
+
+type WebhookResponse struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+	// Secret is only shown when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID            string                   `json:"id"`
+	WebhookID     string                   `json:"webhookId"`
+	EventType     string                   `json:"eventType"`
+	Status        string                   `json:"status"`
+	Attempts      int                      `json:"attempts"`
+	ResponseCode  *int                     `json:"responseCode"`
+	Error         string                   `json:"error"`
+	NextAttemptAt *time.Time               `json:"nextAttemptAt"`
+	ReplayOf      *string                  `json:"replayOf"`
+	CreatedAt     time.Time                `json:"createdAt"`
+	DeliveredAt   *time.Time               `json:"deliveredAt"`
+	Payload       json.RawMessage          `json:"payload,omitempty"`
+	AttemptLog    []WebhookAttemptResponse `json:"attemptLog,omitempty"`
+}
+
+type WebhookAttemptResponse struct {
+	ResponseCode *int      `json:"responseCode"`
+	Error        string    `json:"error"`
+	DurationMs   int       `json:"durationMs"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// WebhookDeliveryPage is one page of a webhook's delivery log, newest first
+type WebhookDeliveryPage struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	NextCursor string                    `json:"nextCursor"`
+}
@@ -0,0 +1,87 @@
+package notifications
+
+import (
+	"fmt"
+	"trello-backend/config"
+	"trello-backend/mailer"
+	"trello-backend/models"
+)
+
+// digestBoard is one board's section of a digest
+type digestBoard struct {
+	Name    string
+	Link    string
+	Entries []models.DigestEntry
+}
+
+// SendDigest emails a user the entries collected since their last digest,
+// grouped by board in the order the boards first come up
+func SendDigest(m mailer.Mailer, user models.User, entries []models.DigestEntry) error {
+	if user.Email == "" || len(entries) == 0 {
+		return nil
+	}
+
+	var boardIDs []string
+	for _, entry := range entries {
+		if entry.BoardID != "" {
+			boardIDs = append(boardIDs, entry.BoardID)
+		}
+	}
+	// Boards deleted since keep their name in the digest
+	var boards []models.Board
+	if err := config.DB.Unscoped().Where("id IN ?", boardIDs).Find(&boards).Error; err != nil {
+		return err
+	}
+	names := make(map[string]string, len(boards))
+	for _, board := range boards {
+		names[board.ID] = board.Name
+	}
+
+	var groups []*digestBoard
+	byBoard := make(map[string]*digestBoard)
+	for _, entry := range entries {
+		group, ok := byBoard[entry.BoardID]
+		if !ok {
+			group = &digestBoard{Name: names[entry.BoardID], Link: boardURL(entry.BoardID)}
+			if group.Name == "" {
+				group.Name = "Other"
+			}
+			byBoard[entry.BoardID] = group
+			groups = append(groups, group)
+		}
+		group.Entries = append(group.Entries, entry)
+	}
+
+	other := models.EmailDeliveryWeekly
+	if user.EmailDelivery == models.EmailDeliveryWeekly {
+		other = models.EmailDeliveryDaily
+	}
+	links, err := footerLinks(user.ID,
+		option{"Get a " + other + " digest instead", PreferenceChange{Delivery: other}},
+		option{"Get an email for each notification", PreferenceChange{Delivery: models.EmailDeliveryImmediate}},
+	)
+	if err != nil {
+		return err
+	}
+
+	period := user.EmailDelivery
+	if period != models.EmailDeliveryWeekly {
+		period = models.EmailDeliveryDaily
+	}
+	subject := fmt.Sprintf("Your %s digest: %d notification", period, len(entries))
+	if len(entries) != 1 {
+		subject += "s"
+	}
+
+	email, err := render("digest", struct {
+		Subject string
+		Boards  []*digestBoard
+		Links   []link
+	}{subject, groups, links})
+	if err != nil {
+		return err
+	}
+	email.To = user.Email
+	email.Subject = subject
+	return m.Send(withUnsubscribe(email, links))
+}
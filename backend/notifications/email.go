@@ -0,0 +1,162 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+	"time"
+	"trello-backend/config"
+	"trello-backend/inbound"
+	"trello-backend/mailer"
+	"trello-backend/models"
+
+	"github.com/google/uuid"
+)
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.ParseFS(templateFiles, "templates/*.html.tmpl"))
+	textTemplates = texttemplate.Must(texttemplate.ParseFS(templateFiles, "templates/*.txt.tmpl"))
+)
+
+// link is a labelled URL in an email
+type link struct {
+	Label string
+	URL   string
+}
+
+// EmailNotifier emails messages to users, right away or in their next digest,
+// depending on their EmailDelivery
+type EmailNotifier struct {
+	Mailer mailer.Mailer
+}
+
+func (e EmailNotifier) Notify(msg Message) error {
+	if msg.User.Email == "" {
+		return nil
+	}
+
+	switch msg.User.EmailDelivery {
+	case models.EmailDeliveryImmediate:
+	case models.EmailDeliveryDaily, models.EmailDeliveryWeekly:
+		entry := models.DigestEntry{
+			ID:        uuid.NewString(),
+			UserID:    msg.User.ID,
+			Type:      msg.Type,
+			Title:     msg.Title,
+			Body:      msg.Body,
+			BoardID:   msg.BoardID,
+			CardID:    msg.CardID,
+			CreatedAt: time.Now().UTC(),
+		}
+		return config.DB.Omit("User").Create(&entry).Error
+	default:
+		return nil
+	}
+
+	links, err := footerLinks(msg.User.ID,
+		option{"Stop emails about " + typeNames[msg.Type], PreferenceChange{Type: msg.Type}},
+		option{"Get a daily digest instead", PreferenceChange{Delivery: models.EmailDeliveryDaily}},
+	)
+	if err != nil {
+		return err
+	}
+	// Replies to emails about a card become comments on it
+	replyTo, marker := "", ""
+	if msg.CardID != "" {
+		replyTo = inbound.ReplyAddress(msg.CardID)
+	}
+	if replyTo != "" {
+		marker = inbound.ReplyMarker
+	}
+	data := struct {
+		Title       string
+		Body        string
+		Link        string
+		Links       []link
+		ReplyMarker string
+	}{msg.Title, msg.Body, boardURL(msg.BoardID), links, marker}
+
+	email, err := render("email", data)
+	if err != nil {
+		return err
+	}
+	email.To = msg.User.Email
+	email.Subject = msg.Title
+	email = withUnsubscribe(email, links)
+	if replyTo != "" {
+		email.Headers["Reply-To"] = replyTo
+	}
+	return e.Mailer.Send(email)
+}
+
+// option is a preference change offered at the bottom of an email
+type option struct {
+	label  string
+	change PreferenceChange
+}
+
+// footerLinks turns the options for a user into links, followed by one to
+// unsubscribe from all email
+func footerLinks(userID string, options ...option) ([]link, error) {
+	options = append(options, option{"Unsubscribe from all emails", PreferenceChange{Delivery: models.EmailDeliveryOff}})
+
+	links := make([]link, len(options))
+	for i, o := range options {
+		o.change.UserID = userID
+		url, err := o.change.URL()
+		if err != nil {
+			return nil, err
+		}
+		links[i] = link{o.label, url}
+	}
+	return links, nil
+}
+
+// withUnsubscribe adds the headers mail clients use for their own unsubscribe
+// button, pointing at the last footer link (RFC 8058)
+func withUnsubscribe(msg mailer.Message, links []link) mailer.Message {
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + links[len(links)-1].URL + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return msg
+}
+
+// render fills in the HTML and plain-text versions of an email template
+func render(name string, data interface{}) (mailer.Message, error) {
+	var html, text bytes.Buffer
+	if err := htmlTemplates.ExecuteTemplate(&html, name+".html.tmpl", data); err != nil {
+		return mailer.Message{}, err
+	}
+	if err := textTemplates.ExecuteTemplate(&text, name+".txt.tmpl", data); err != nil {
+		return mailer.Message{}, err
+	}
+	return mailer.Message{HTML: html.String(), Text: text.String()}, nil
+}
+
+// boardURL links to a board in the app, or returns "" without a board
+func boardURL(boardID string) string {
+	if boardID == "" {
+		return ""
+	}
+	return config.AppURL() + "/boards/detail?boardId=" + boardID
+}
+
+// RenderPreferencePage writes the page shown for an email preference link,
+// asking to confirm the change or, once applied, saying it was made
+func RenderPreferencePage(w io.Writer, change PreferenceChange, applied bool) error {
+	url, err := change.URL()
+	if err != nil {
+		return err
+	}
+	return htmlTemplates.ExecuteTemplate(w, "preferences.html.tmpl", struct {
+		Description string
+		URL         string
+		Applied     bool
+	}{change.Describe(), url, applied})
+}
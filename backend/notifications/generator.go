@@ -0,0 +1,143 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+	"trello-backend/subscriptions"
+)
+
+// subject holds the fields of an event payload the notifications are built from.
+// Payloads are either a model or a small map, so matching is by name only.
+type subject struct {
+	ID        string `json:"id"`
+	ListID    string `json:"listId"`
+	CardID    string `json:"cardId"`
+	UserID    string `json:"userId"`
+	Title     string `json:"title"`
+	Name      string `json:"name"`
+	Body      string `json:"body"`
+	CommentID string `json:"commentId"`
+	CardTitle string `json:"cardTitle"`
+	BoardName string `json:"boardName"`
+	// Mentioned holds the IDs of the users mentioned by a comment or card
+	Mentioned []string `json:"mentioned"`
+}
+
+// changeVerbs describes the board, list and card changes sent to watchers
+var changeVerbs = map[string]string{
+	events.BoardUpdated: "updated",
+	events.ListCreated:  "created",
+	events.ListUpdated:  "updated",
+	events.ListMoved:    "moved",
+	events.ListDeleted:  "deleted",
+	events.CardCreated:  "created",
+	events.CardUpdated:  "updated",
+	events.CardMoved:    "moved",
+	events.CardDeleted:  "deleted",
+}
+
+// FromEvent notifies the users a board event concerns: mentioned users,
+// assignees, invited members, and the watchers of what changed. The user who
+// made the change is never notified. It is registered with events.Handle.
+func FromEvent(event events.Event) {
+	var s subject
+	if err := json.Unmarshal(event.Data, &s); err != nil {
+		log.Printf("Failed to read %s event: %v", event.Type, err)
+		return
+	}
+
+	// Scheduler events such as card.due have no actor and nothing to notify
+	if event.ActorID == "" {
+		return
+	}
+	var actor models.User
+	if err := config.DB.Where("id = ?", event.ActorID).First(&actor).Error; err != nil {
+		log.Printf("Failed to find the actor of %s event: %v", event.Type, err)
+		return
+	}
+
+	msg := Message{BoardID: event.BoardID}
+	var recipients []models.User
+	var err error
+
+	switch event.Type {
+	case events.MentionCreated:
+		msg.Type = TypeMention
+		msg.CardID = s.CardID
+		msg.Title = fmt.Sprintf("You were mentioned on \"%s\"", s.CardTitle)
+		if s.CommentID != "" {
+			msg.Body = fmt.Sprintf("%s mentioned you in a comment on \"%s\".", actor.Username, s.CardTitle)
+		} else {
+			msg.Body = fmt.Sprintf("%s mentioned you in the description of \"%s\".", actor.Username, s.CardTitle)
+		}
+		err = config.DB.Where("id IN ?", s.Mentioned).Find(&recipients).Error
+	case events.CardAssigned:
+		msg.Type = TypeAssignment
+		msg.CardID = s.CardID
+		msg.Title = fmt.Sprintf("You were assigned to \"%s\"", s.Title)
+		msg.Body = fmt.Sprintf("%s assigned you to \"%s\".", actor.Username, s.Title)
+		err = config.DB.Where("id = ?", s.UserID).Find(&recipients).Error
+	case events.CommentCreated:
+		msg.Type = TypeComment
+		msg.CardID = s.CardID
+		msg.Title = fmt.Sprintf("New comment on \"%s\"", s.CardTitle)
+		msg.Body = fmt.Sprintf("%s commented: %s", actor.Username, s.Body)
+		recipients, err = subscriptions.Watchers(config.DB, changeTarget(event.BoardID, s.ListID, s.CardID))
+		// Mentioned watchers hear about the comment through the mention
+		recipients = slices.DeleteFunc(recipients, func(u models.User) bool { return slices.Contains(s.Mentioned, u.ID) })
+	case events.MemberAdded:
+		msg.Type = TypeInvitation
+		msg.Title = fmt.Sprintf("You were added to \"%s\"", s.BoardName)
+		msg.Body = fmt.Sprintf("%s added you to the board \"%s\".", actor.Username, s.BoardName)
+		err = config.DB.Where("id = ?", s.UserID).Find(&recipients).Error
+	case events.CardCreated, events.CardUpdated, events.CardMoved, events.CardDeleted:
+		msg.Type = TypeWatchedChange
+		msg.CardID = s.ID
+		msg.Title = fmt.Sprintf("Card \"%s\" was %s", s.Title, changeVerbs[event.Type])
+		msg.Body = fmt.Sprintf("%s by %s.", msg.Title, actor.Username)
+		recipients, err = subscriptions.Watchers(config.DB, changeTarget(event.BoardID, s.ListID, s.ID))
+	case events.BoardUpdated:
+		msg.Type = TypeWatchedChange
+		msg.Title = fmt.Sprintf("Board \"%s\" was %s", s.Name, changeVerbs[event.Type])
+		msg.Body = fmt.Sprintf("%s by %s.", msg.Title, actor.Username)
+		recipients, err = subscriptions.Watchers(config.DB, subscriptions.Target{BoardID: event.BoardID})
+	case events.ListCreated, events.ListUpdated, events.ListMoved, events.ListDeleted:
+		msg.Type = TypeWatchedChange
+		msg.Title = fmt.Sprintf("List \"%s\" was %s", s.Name, changeVerbs[event.Type])
+		msg.Body = fmt.Sprintf("%s by %s.", msg.Title, actor.Username)
+		recipients, err = subscriptions.Watchers(config.DB, changeTarget(event.BoardID, s.ID, ""))
+	default:
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to find recipients of %s event: %v", event.Type, err)
+		return
+	}
+
+	for _, user := range recipients {
+		if user.ID == event.ActorID {
+			continue
+		}
+		msg.User = user
+		if err := Default().Notify(msg); err != nil {
+			log.Printf("Failed to notify %s: %v", user.ID, err)
+		}
+	}
+}
+
+// changeTarget returns whose watchers hear about a change to a list or card on
+// a board. Moves between boards are published on both; the list is on the
+// target board only, so the source board's event reaches just its own watchers
+// and list and card watchers are told once.
+func changeTarget(boardID, listID, cardID string) subscriptions.Target {
+	var list models.List
+	if err := config.DB.Unscoped().Where("id = ?", listID).First(&list).Error; err != nil || list.BoardID != boardID {
+		return subscriptions.Target{BoardID: boardID}
+	}
+	return subscriptions.Target{BoardID: boardID, ListID: listID, CardID: cardID}
+}
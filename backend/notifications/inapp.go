@@ -0,0 +1,26 @@
+package notifications
+
+import (
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// InAppNotifier stores messages in the user's notification inbox
+type InAppNotifier struct{}
+
+func (InAppNotifier) Notify(msg Message) error {
+	notification := models.Notification{
+		ID:        uuid.NewString(),
+		UserID:    msg.User.ID,
+		Type:      msg.Type,
+		Title:     msg.Title,
+		Body:      msg.Body,
+		BoardID:   msg.BoardID,
+		CardID:    msg.CardID,
+		CreatedAt: time.Now().UTC(),
+	}
+	return config.DB.Create(&notification).Error
+}
@@ -0,0 +1,130 @@
+package notifications
+
+import (
+	"errors"
+	"net/url"
+	"trello-backend/config"
+	"trello-backend/middlewares"
+	"trello-backend/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm/clause"
+)
+
+// PreferenceChange is a change to a user's email settings made from a link in
+// an email, so it works without signing in
+type PreferenceChange struct {
+	UserID string
+	// Type turns off email for one notification type
+	Type string
+	// Delivery switches when email is sent: one of the models.EmailDelivery* values
+	Delivery string
+}
+
+// preferenceTokenPurpose keeps preference tokens from being accepted as logins
+// and login tokens from being accepted here
+const preferenceTokenPurpose = "email_preferences"
+
+type preferenceClaims struct {
+	Purpose  string `json:"purpose"`
+	Type     string `json:"type,omitempty"`
+	Delivery string `json:"delivery,omitempty"`
+	jwt.RegisteredClaims
+}
+
+var errInvalidPreferenceToken = errors.New("invalid preference token")
+
+// typeNames describes notification types in emails
+var typeNames = map[string]string{
+	TypeMention:       "mentions",
+	TypeAssignment:    "assignments",
+	TypeComment:       "comments on cards you watch",
+	TypeDueReminder:   "due-date reminders",
+	TypeInvitation:    "board invitations",
+	TypeWatchedChange: "changes to things you watch",
+}
+
+// Token signs the change. It does not expire, as unsubscribe links have to keep working.
+func (p PreferenceChange) Token() (string, error) {
+	claims := preferenceClaims{
+		Purpose:          preferenceTokenPurpose,
+		Type:             p.Type,
+		Delivery:         p.Delivery,
+		RegisteredClaims: jwt.RegisteredClaims{Subject: p.UserID},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(middlewares.JwtSecret)
+}
+
+// URL returns the link that makes the change: a confirmation page, which also
+// accepts the one-click POST from mail clients
+func (p PreferenceChange) URL() (string, error) {
+	token, err := p.Token()
+	if err != nil {
+		return "", err
+	}
+	return config.APIURL() + "/email/preferences?token=" + url.QueryEscape(token), nil
+}
+
+// ParsePreferenceToken checks a token made by PreferenceChange.Token and returns its change
+func ParsePreferenceToken(token string) (PreferenceChange, error) {
+	var claims preferenceClaims
+	parsed, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return middlewares.JwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}))
+	if err != nil || !parsed.Valid || claims.Purpose != preferenceTokenPurpose || claims.Subject == "" {
+		return PreferenceChange{}, errInvalidPreferenceToken
+	}
+
+	change := PreferenceChange{UserID: claims.Subject, Type: claims.Type, Delivery: claims.Delivery}
+	if _, ok := typeNames[change.Type]; change.Type != "" && !ok {
+		return PreferenceChange{}, errInvalidPreferenceToken
+	}
+	if change.Delivery != "" && !ValidEmailDelivery(change.Delivery) {
+		return PreferenceChange{}, errInvalidPreferenceToken
+	}
+	return change, nil
+}
+
+// Describe says what the change does, to finish the sentence "Confirm that you want to ..."
+func (p PreferenceChange) Describe() string {
+	switch {
+	case p.Type != "":
+		return "stop getting emails about " + typeNames[p.Type]
+	case p.Delivery == models.EmailDeliveryOff:
+		return "stop getting notification emails"
+	case p.Delivery == models.EmailDeliveryImmediate:
+		return "get an email for each notification"
+	default:
+		return "get a " + p.Delivery + " digest email instead of one email per notification"
+	}
+}
+
+// Apply saves the change
+func (p PreferenceChange) Apply() error {
+	if p.Type != "" {
+		// In-app delivery is left as it is
+		preference := models.NotificationPreference{UserID: p.UserID, Type: p.Type, InApp: true, Email: false}
+		if err := config.DB.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+			DoUpdates: clause.AssignmentColumns([]string{"email"}),
+		}).Create(&preference).Error; err != nil {
+			return err
+		}
+	}
+	if p.Delivery != "" {
+		if err := config.DB.Model(&models.User{}).Where("id = ?", p.UserID).
+			Update("email_delivery", p.Delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidEmailDelivery reports whether delivery is one of the models.EmailDelivery* values
+func ValidEmailDelivery(delivery string) bool {
+	switch delivery {
+	case models.EmailDeliveryOff, models.EmailDeliveryImmediate, models.EmailDeliveryDaily, models.EmailDeliveryWeekly:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package notifications
+
+import (
+	"errors"
+	"sync"
+	"trello-backend/mailer"
+	"trello-backend/models"
+)
+
+// Message is a notification addressed to one user
+type Message struct {
+	User    models.User
+	Type    string
+	Title   string
+	Body    string
+	BoardID string
+	CardID  string
+}
+
+const (
+	TypeDueReminder   = "due_reminder"
+	TypeWatchedChange = "watched_change"
+	TypeAssignment    = "assignment"
+	TypeComment       = "comment"
+	TypeInvitation    = "board_invitation"
+	TypeMention       = "mention"
+)
+
+// Notifier delivers messages to users over a single channel
+type Notifier interface {
+	Notify(msg Message) error
+}
+
+// multiNotifier fans a message out to several channels
+type multiNotifier []Notifier
+
+func (m multiNotifier) Notify(msg Message) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultNotifier Notifier
+)
+
+// Default returns the in-app notifier, plus email when a mailer is configured,
+// each skipping the types the user has turned off for it. It is built once and
+// shared by the jobs and the event handlers.
+func Default() Notifier {
+	defaultOnce.Do(func() {
+		notifiers := multiNotifier{preferenceFilter{ChannelInApp, InAppNotifier{}}}
+		if m := mailer.Default(); m != nil {
+			notifiers = append(notifiers, preferenceFilter{ChannelEmail, EmailNotifier{Mailer: m}})
+		}
+		defaultNotifier = notifiers
+	})
+	return defaultNotifier
+}
@@ -0,0 +1,53 @@
+package notifications
+
+import (
+	"trello-backend/config"
+	"trello-backend/models"
+)
+
+// Channels a preference can turn off
+const (
+	ChannelInApp = "in_app"
+	ChannelEmail = "email"
+)
+
+// Types lists the notification types users can set preferences for
+var Types = []string{
+	TypeMention,
+	TypeAssignment,
+	TypeComment,
+	TypeDueReminder,
+	TypeInvitation,
+	TypeWatchedChange,
+}
+
+// Enabled reports whether a user gets notifications of a type on a channel.
+// Everything is enabled until the user says otherwise.
+func Enabled(userID, notificationType, channel string) (bool, error) {
+	var preference models.NotificationPreference
+	result := config.DB.Where("user_id = ? AND type = ?", userID, notificationType).Limit(1).Find(&preference)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return true, nil
+	}
+	if channel == ChannelEmail {
+		return preference.Email, nil
+	}
+	return preference.InApp, nil
+}
+
+// preferenceFilter drops messages the user has turned off for its channel
+type preferenceFilter struct {
+	channel string
+	next    Notifier
+}
+
+func (f preferenceFilter) Notify(msg Message) error {
+	enabled, err := Enabled(msg.User.ID, msg.Type, f.channel)
+	if err != nil || !enabled {
+		return err
+	}
+	return f.next.Notify(msg)
+}
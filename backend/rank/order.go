@@ -0,0 +1,97 @@
+package rank
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Scope is the set of siblings an item is ordered among
+type Scope struct {
+	Table  string
+	Column string
+	ID     string
+}
+
+// CardsIn orders the cards of a list
+func CardsIn(listID string) Scope {
+	return Scope{Table: "cards", Column: "list_id", ID: listID}
+}
+
+// ListsIn orders the lists of a board
+func ListsIn(boardID string) Scope {
+	return Scope{Table: "lists", Column: "board_id", ID: boardID}
+}
+
+func (s Scope) siblings(tx *gorm.DB) *gorm.DB {
+	return tx.Table(s.Table).Where(s.Column+" = ? AND deleted_at IS NULL", s.ID)
+}
+
+// At returns the key that puts an item at index among the visible items of scope.
+// excludeID is the item being moved, so it doesn't count as its own neighbour.
+// Indexes past the end place the item last.
+func At(tx *gorm.DB, scope Scope, index int, excludeID string) (string, error) {
+	key, err := at(tx, scope, index, excludeID)
+	if errors.Is(err, ErrNoRoom) {
+		// Two siblings share a key; spread them out and try again
+		if err := Rebalance(tx, scope); err != nil {
+			return "", err
+		}
+		key, err = at(tx, scope, index, excludeID)
+	}
+	return key, err
+}
+
+func at(tx *gorm.DB, scope Scope, index int, excludeID string) (string, error) {
+	visible := func() *gorm.DB {
+		return scope.siblings(tx).Where("archived_at IS NULL AND id <> ?", excludeID)
+	}
+
+	var prev, next string
+	if index <= 0 {
+		var first []string
+		if err := visible().Order("rank, id").Limit(1).Pluck("rank", &first).Error; err != nil {
+			return "", err
+		}
+		if len(first) > 0 {
+			next = first[0]
+		}
+		return Between(prev, next)
+	}
+
+	var neighbours []string
+	if err := visible().Order("rank, id").Offset(index-1).Limit(2).Pluck("rank", &neighbours).Error; err != nil {
+		return "", err
+	}
+	switch len(neighbours) {
+	case 2:
+		prev, next = neighbours[0], neighbours[1]
+	case 1:
+		prev = neighbours[0]
+	default:
+		// Past the end: go after the last item
+		var last []string
+		if err := visible().Order("rank DESC, id DESC").Limit(1).Pluck("rank", &last).Error; err != nil {
+			return "", err
+		}
+		if len(last) > 0 {
+			prev = last[0]
+		}
+	}
+	return Between(prev, next)
+}
+
+// Rebalance rewrites every key in scope with evenly spaced ones, keeping the order
+func Rebalance(tx *gorm.DB, scope Scope) error {
+	var ids []string
+	if err := scope.siblings(tx).Order("rank, id").Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	for i, key := range Spread(len(ids)) {
+		if err := tx.Table(scope.Table).Where("id = ?", ids[i]).Update("rank", key).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package rank implements lexicographic rank keys for ordering lists and cards.
+// A new key can always be generated between two existing ones, so moving an
+// item only rewrites that item's key.
+package rank
+
+import (
+	"errors"
+	"strings"
+)
+
+const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// MaxLength is the key length past which a list or board should be rebalanced
+const MaxLength = 12
+
+var ErrNoRoom = errors.New("rank: keys are not in ascending order")
+
+// Between returns a key that sorts strictly after prev and before next.
+// An empty prev means the start of the list and an empty next means the end.
+func Between(prev, next string) (string, error) {
+	if next != "" && prev >= next {
+		return "", ErrNoRoom
+	}
+	if !valid(prev) || !valid(next) {
+		return "", errors.New("rank: invalid key")
+	}
+	return midpoint(prev, next), nil
+}
+
+// Spread returns n evenly spaced keys in ascending order
+func Spread(n int) []string {
+	keys := make([]string, n)
+	if n == 0 {
+		return keys
+	}
+
+	// Use the fewest digits that leave a gap between every pair of keys
+	width, space := 1, len(digits)
+	for space <= n {
+		width++
+		space *= len(digits)
+	}
+	step := space / (n + 1)
+
+	for i := range keys {
+		keys[i] = encode((i+1)*step, width)
+	}
+	return keys
+}
+
+// midpoint is the fractional-indexing midpoint of a and b, where b == "" is
+// treated as infinity. Keys never end in the zero digit, so there is always
+// room below them.
+func midpoint(a, b string) string {
+	if b != "" {
+		// Keep the common prefix and find the midpoint of the rest
+		n := 0
+		for n < len(b) && digitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpoint(a[min(n, len(a)):], b[n:])
+		}
+	}
+
+	digitA := 0
+	if a != "" {
+		digitA = strings.IndexByte(digits, a[0])
+	}
+	digitB := len(digits)
+	if b != "" {
+		digitB = strings.IndexByte(digits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(digits[(digitA+digitB+1)/2])
+	}
+	if len(b) > 1 {
+		return b[:1]
+	}
+	rest := ""
+	if a != "" {
+		rest = a[1:]
+	}
+	return string(digits[digitA]) + midpoint(rest, "")
+}
+
+func digitAt(s string, i int) byte {
+	if i < len(s) {
+		return s[i]
+	}
+	return digits[0]
+}
+
+// encode writes v in base 36 using exactly width digits, dropping trailing zeros
+func encode(v, width int) string {
+	buf := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		buf[i] = digits[v%len(digits)]
+		v /= len(digits)
+	}
+	return strings.TrimRight(string(buf), digits[:1])
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, digits[:1])
+}
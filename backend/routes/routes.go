@@ -6,6 +6,7 @@ import (
 	"trello-backend/config"
 	"trello-backend/controllers"
 	"trello-backend/middlewares"
+	"trello-backend/migrations"
 
 	"github.com/gin-contrib/cors"
 
@@ -14,13 +15,14 @@ import (
 
 func SetupRouter() *gin.Engine {
 	config.ConnectDB()
+	migrations.Migrate()
 	router := gin.Default()
 
 	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:3000", "http://localhost:3000/"}, // Allow with/without trailing slash
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowOrigins:     config.AllowedOrigins,
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "If-Match"},
+		ExposeHeaders:    []string{"Content-Length", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -31,6 +33,8 @@ func SetupRouter() *gin.Engine {
 		auth.POST("/login", controllers.Login)
 		auth.POST("/google", controllers.LoginWithGoogle)
 		auth.GET("/me", middlewares.AuthMiddleware(), controllers.GetCurrentUser)
+		auth.GET("/me/reminders", middlewares.AuthMiddleware(), controllers.GetReminderSettings)
+		auth.PUT("/me/reminders", middlewares.AuthMiddleware(), controllers.UpdateReminderSettings)
 	}
 
 	board := router.Group("/board")
@@ -40,8 +44,26 @@ func SetupRouter() *gin.Engine {
 		board.GET("/", controllers.GetAllBoards)
 		board.GET("/:boardId", controllers.GetBoard)
 		board.PUT("/:boardId", controllers.UpdateBoard)
+		board.PATCH("/:boardId", controllers.PatchBoard)
 		board.DELETE("/:boardId", controllers.DeleteBoard)
 
+		// archive and trash routes
+		board.GET("/trash", controllers.GetDeletedBoards)
+		board.GET("/templates", controllers.GetBoardTemplates)
+		board.PUT("/:boardId/template", controllers.SetBoardTemplate)
+		board.GET("/:boardId/card-templates", controllers.GetBoardCardTemplates)
+		board.POST("/:boardId/archive", controllers.ArchiveBoard)
+		board.POST("/:boardId/unarchive", controllers.UnarchiveBoard)
+		board.POST("/:boardId/restore", controllers.RestoreBoard)
+		board.POST("/:boardId/copy", controllers.CopyBoard)
+		board.GET("/:boardId/trash", controllers.GetBoardTrash)
+		board.POST("/:boardId/lists/:listId/archive", controllers.ArchiveList)
+		board.POST("/:boardId/lists/:listId/unarchive", controllers.UnarchiveList)
+		board.POST("/:boardId/lists/:listId/restore", controllers.RestoreList)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/archive", controllers.ArchiveCard)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/unarchive", controllers.UnarchiveCard)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/restore", controllers.RestoreCard)
+
 		// members routes
 		board.GET("/users", controllers.GetAllUsers)
 		board.POST("/:boardId/members", controllers.AddBoardMember)
@@ -52,16 +74,107 @@ func SetupRouter() *gin.Engine {
 		board.GET("/:boardId/lists", controllers.GetBoardLists)
 		board.GET("/:boardId/lists/:listId", controllers.GetBoardList)
 		board.PUT("/:boardId/lists/:listId", controllers.UpdateBoardList)
+		board.PATCH("/:boardId/lists/:listId", controllers.PatchBoardList)
 		board.DELETE("/:boardId/lists/:listId", controllers.DeleteBoardList)
+		board.POST("/:boardId/lists/:listId/move", controllers.MoveBoardList)
+		board.POST("/:boardId/lists/:listId/copy", controllers.CopyBoardList)
 
 		board.POST("/:boardId/lists/:listId/cards", controllers.CreateCard)
 		board.GET("/:boardId/lists/:listId/cards", controllers.GetListCards)
 		board.GET("/:boardId/lists/:listId/cards/:cardId", controllers.GetCardByID)
 		board.PUT("/:boardId/lists/:listId/cards/:cardId", controllers.UpdateCard)
+		board.PATCH("/:boardId/lists/:listId/cards/:cardId", controllers.PatchCard)
 		board.DELETE("/:boardId/lists/:listId/cards/:cardId", controllers.DeleteCard)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/assignees", controllers.AddCardAssignee)
+		board.DELETE("/:boardId/lists/:listId/cards/:cardId/assignees/:userId", controllers.RemoveCardAssignee)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/labels", controllers.AddCardLabel)
+		board.DELETE("/:boardId/lists/:listId/cards/:cardId/labels/:labelId", controllers.RemoveCardLabel)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/move", controllers.MoveCardToBoard)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/blocks", controllers.AddCardDependency)
+		board.DELETE("/:boardId/lists/:listId/cards/:cardId/blocks/:blockedCardId", controllers.RemoveCardDependency)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/copy", controllers.CopyCard)
+		board.PUT("/:boardId/lists/:listId/cards/:cardId/template", controllers.SetCardTemplate)
+
+		// Watching and comments
+		board.POST("/:boardId/watch", controllers.WatchBoard)
+		board.DELETE("/:boardId/watch", controllers.UnwatchBoard)
+		board.POST("/:boardId/lists/:listId/watch", controllers.WatchList)
+		board.DELETE("/:boardId/lists/:listId/watch", controllers.UnwatchList)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/watch", controllers.WatchCard)
+		board.DELETE("/:boardId/lists/:listId/cards/:cardId/watch", controllers.UnwatchCard)
+		board.GET("/:boardId/lists/:listId/cards/:cardId/comments", controllers.GetCardComments)
+		board.POST("/:boardId/lists/:listId/cards/:cardId/comments", controllers.CreateComment)
+		board.PUT("/:boardId/lists/:listId/cards/:cardId/comments/:commentId", controllers.UpdateComment)
+		board.DELETE("/:boardId/lists/:listId/cards/:cardId/comments/:commentId", controllers.DeleteComment)
+
+		// Email in and attachments
+		board.GET("/:boardId/email-address", controllers.GetBoardEmailAddress)
+		board.GET("/:boardId/lists/:listId/cards/:cardId/attachments", controllers.GetCardAttachments)
+		board.GET("/:boardId/lists/:listId/cards/:cardId/attachments/:attachmentId", controllers.DownloadCardAttachment)
+
+		// Live updates
+		board.GET("/:boardId/events", controllers.StreamBoardEvents)
+		board.GET("/:boardId/socket", controllers.BoardSocket)
+
+		// Activity
+		board.GET("/:boardId/activity", controllers.GetBoardActivity)
+		board.GET("/:boardId/lists/:listId/cards/:cardId/activity", controllers.GetCardActivity)
+
+		// Labels
+		board.GET("/:boardId/labels", controllers.GetBoardLabels)
+		board.POST("/:boardId/labels", controllers.CreateLabel)
+		board.PUT("/:boardId/labels/:labelId", controllers.UpdateLabel)
+		board.DELETE("/:boardId/labels/:labelId", controllers.DeleteLabel)
+
+		// Custom fields
+		board.GET("/:boardId/custom-fields", controllers.GetCustomFields)
+		board.POST("/:boardId/custom-fields", controllers.CreateCustomField)
+		board.PUT("/:boardId/custom-fields/:fieldId", controllers.UpdateCustomField)
+		board.DELETE("/:boardId/custom-fields/:fieldId", controllers.DeleteCustomField)
+		board.PUT("/:boardId/lists/:listId/cards/:cardId/custom-fields/:fieldId", controllers.SetCardFieldValue)
+
+		// Webhooks
+		board.GET("/:boardId/webhooks", controllers.GetWebhooks)
+		board.POST("/:boardId/webhooks", controllers.CreateWebhook)
+		board.PUT("/:boardId/webhooks/:webhookId", controllers.UpdateWebhook)
+		board.DELETE("/:boardId/webhooks/:webhookId", controllers.DeleteWebhook)
+		board.GET("/:boardId/webhooks/:webhookId/deliveries", controllers.GetWebhookDeliveries)
+		board.GET("/:boardId/webhooks/:webhookId/deliveries/:deliveryId", controllers.GetWebhookDelivery)
+		board.POST("/:boardId/webhooks/:webhookId/deliveries/:deliveryId/replay", controllers.ReplayWebhookDelivery)
+
+		// Automation
+		board.GET("/:boardId/automations", controllers.GetAutomationRules)
+		board.POST("/:boardId/automations", controllers.CreateAutomationRule)
+		board.PUT("/:boardId/automations/:ruleId", controllers.UpdateAutomationRule)
+		board.DELETE("/:boardId/automations/:ruleId", controllers.DeleteAutomationRule)
+		board.POST("/:boardId/automations/:ruleId/test", controllers.TestAutomationRule)
+		board.GET("/:boardId/automation-runs", controllers.GetAutomationRuns)
 
 		board.GET("/:boardId/full", controllers.GetBoardWithLists)
 	}
 
+	notifications := router.Group("/notifications")
+	notifications.Use(middlewares.AuthMiddleware())
+	{
+		notifications.GET("", controllers.GetNotifications)
+		notifications.GET("/unread-count", controllers.GetUnreadNotificationCount)
+		notifications.POST("/read-all", controllers.MarkAllNotificationsRead)
+		notifications.POST("/:notificationId/read", controllers.MarkNotificationRead)
+		notifications.GET("/preferences", controllers.GetNotificationPreferences)
+		notifications.PUT("/preferences", controllers.UpdateNotificationPreferences)
+		notifications.GET("/email", controllers.GetEmailSettings)
+		notifications.PUT("/email", controllers.UpdateEmailSettings)
+	}
+
+	// Links in notification emails carry their own token
+	email := router.Group("/email")
+	{
+		email.GET("/preferences", controllers.ShowEmailPreferenceChange)
+		email.POST("/preferences", controllers.ApplyEmailPreferenceChange)
+	}
+
+	// Mail providers forward inbound email here, authenticated by INBOUND_EMAIL_SECRET
+	router.POST("/inbound/email", controllers.ReceiveInboundEmail)
+
 	return router
 }
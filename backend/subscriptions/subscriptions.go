@@ -0,0 +1,95 @@
+// Package subscriptions keeps track of who watches boards, lists and cards, and
+// works out who to notify when one of them changes.
+package subscriptions
+
+import (
+	"trello-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Target identifies what changed. Watchers of each non-empty level are included,
+// so a change to a card reaches the watchers of its list and board as well.
+type Target struct {
+	BoardID string
+	ListID  string
+	CardID  string
+}
+
+// CardTarget returns the target for a card whose List is loaded
+func CardTarget(card models.Card) Target {
+	return Target{BoardID: card.List.BoardID, ListID: card.ListID, CardID: card.ID}
+}
+
+// ListTarget returns the target for a list
+func ListTarget(list models.List) Target {
+	return Target{BoardID: list.BoardID, ListID: list.ID}
+}
+
+// Watch subscribes a user to an entity. Watching twice is not an error.
+func Watch(tx *gorm.DB, userID, entityType, entityID string) error {
+	subscription := models.Subscription{UserID: userID, EntityType: entityType, EntityID: entityID}
+	return tx.Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&subscription).Error
+}
+
+// Unwatch removes a user's subscription to an entity
+func Unwatch(tx *gorm.DB, userID, entityType, entityID string) error {
+	return tx.Where("user_id = ? AND entity_type = ? AND entity_id = ?", userID, entityType, entityID).
+		Delete(&models.Subscription{}).Error
+}
+
+// Watched returns which of the given entities a user watches directly. entityIDs
+// is a slice of IDs or a subquery selecting them.
+func Watched(tx *gorm.DB, userID, entityType string, entityIDs interface{}) (map[string]bool, error) {
+	var ids []string
+	if err := tx.Model(&models.Subscription{}).
+		Where("user_id = ? AND entity_type = ? AND entity_id IN (?)", userID, entityType, entityIDs).
+		Pluck("entity_id", &ids).Error; err != nil {
+		return nil, err
+	}
+
+	watched := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		watched[id] = true
+	}
+	return watched, nil
+}
+
+// Watchers returns the users watching the target, each listed once
+func Watchers(tx *gorm.DB, target Target) ([]models.User, error) {
+	levels := tx.Where("1 = 0")
+	if target.BoardID != "" {
+		levels = levels.Or("entity_type = ? AND entity_id = ?", models.EntityBoard, target.BoardID)
+	}
+	if target.ListID != "" {
+		levels = levels.Or("entity_type = ? AND entity_id = ?", models.EntityList, target.ListID)
+	}
+	if target.CardID != "" {
+		levels = levels.Or("entity_type = ? AND entity_id = ?", models.EntityCard, target.CardID)
+	}
+
+	query := tx.Where("id IN (?)", tx.Model(&models.Subscription{}).Select("user_id").Where(levels))
+	if target.BoardID != "" {
+		// Subscriptions outlive a member leaving the board; they stop counting then
+		query = query.Where("id IN (?) OR id IN (?)",
+			tx.Model(&models.BoardMember{}).Select("user_id").Where("board_id = ?", target.BoardID),
+			tx.Model(&models.Board{}).Select("owner_id").Where("id = ?", target.BoardID))
+	}
+
+	var users []models.User
+	err := query.Find(&users).Error
+	return users, err
+}
+
+// UnwatchBoard removes a user's subscriptions to a board and to every list and
+// card on it, including ones in the trash, for when the user leaves the board
+func UnwatchBoard(tx *gorm.DB, userID, boardID string) error {
+	lists := tx.Unscoped().Model(&models.List{}).Select("id").Where("board_id = ?", boardID)
+	cards := tx.Unscoped().Model(&models.Card{}).Select("id").Where("list_id IN (?)", lists)
+	return tx.Where("user_id = ?", userID).
+		Where(tx.Where("entity_type = ? AND entity_id = ?", models.EntityBoard, boardID).
+			Or("entity_type = ? AND entity_id IN (?)", models.EntityList, lists).
+			Or("entity_type = ? AND entity_id IN (?)", models.EntityCard, cards)).
+		Delete(&models.Subscription{}).Error
+}
@@ -0,0 +1,57 @@
+package subscriptions
+
+import (
+	"testing"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/testdb"
+)
+
+func TestFormerMembersStopWatching(t *testing.T) {
+	testdb.Open(t, &models.User{}, &models.Board{}, &models.List{}, &models.Card{}, &models.Subscription{})
+	owner := models.User{ID: "owner", Username: "owner", Email: "owner@example.com", Password: "x"}
+	member := models.User{ID: "member", Username: "member", Email: "member@example.com", Password: "x"}
+	left := models.User{ID: "left", Username: "left", Email: "left@example.com", Password: "x"}
+	board := models.Board{ID: "board", Name: "Board", OwnerID: owner.ID, Members: []models.User{member, left}}
+	list := models.List{ID: "list", Name: "List", BoardID: board.ID}
+	card := models.Card{ID: "card", Title: "Card", ListID: list.ID}
+	for _, record := range []interface{}{&owner, &member, &left, &board, &list, &card} {
+		if err := config.DB.Omit("Owner", "Board", "List").Create(record).Error; err != nil {
+			t.Fatalf("Failed to create %T: %v", record, err)
+		}
+	}
+	for _, user := range []string{owner.ID, member.ID, left.ID} {
+		Watch(config.DB, user, models.EntityCard, card.ID)
+		Watch(config.DB, user, models.EntityList, list.ID)
+	}
+	Watch(config.DB, left.ID, models.EntityBoard, board.ID)
+
+	// Leaving the board without unwatching it
+	config.DB.Where("board_id = ? AND user_id = ?", board.ID, left.ID).Delete(&models.BoardMember{})
+	watchers, err := Watchers(config.DB, CardTarget(models.Card{ID: card.ID, ListID: list.ID, List: list}))
+	if err != nil {
+		t.Fatalf("Watchers: %v", err)
+	}
+	if len(watchers) != 2 {
+		t.Fatalf("got %d watchers, want the owner and the remaining member", len(watchers))
+	}
+	for _, user := range watchers {
+		if user.ID == left.ID {
+			t.Fatal("a former member is still a watcher")
+		}
+	}
+
+	if err := UnwatchBoard(config.DB, left.ID, board.ID); err != nil {
+		t.Fatalf("UnwatchBoard: %v", err)
+	}
+	var remaining []models.Subscription
+	config.DB.Find(&remaining)
+	if len(remaining) != 4 {
+		t.Fatalf("%d subscriptions left, want the other users' 4", len(remaining))
+	}
+	for _, subscription := range remaining {
+		if subscription.UserID == left.ID {
+			t.Fatalf("%s %s is still watched by the former member", subscription.EntityType, subscription.EntityID)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"syscall"
+)
+
+var (
+	errInvalidURL   = errors.New("webhook URL must be an absolute http or https URL")
+	errInternalHost = errors.New("webhook URL must not point at a loopback, private or link-local address")
+
+	// sharedAddressSpace is the carrier-grade NAT range, internal like the private ones
+	sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+	// blocked reports whether deliveries may not be sent to ip. Tests swap it
+	// out to reach servers on the loopback interface.
+	blocked = internalIP
+)
+
+// internalIP reports whether ip is on this host or an internal network rather
+// than the public internet
+func internalIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// CheckURL reports why a webhook can't be sent to rawURL, or nil if it can. The
+// host must resolve, and only to public addresses.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return errInvalidURL
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return errors.New("webhook URL host could not be resolved")
+	}
+	for _, addr := range addrs {
+		if blocked(addr.IP) {
+			return errInternalHost
+		}
+	}
+	return nil
+}
+
+// checkDial refuses connections to internal addresses. It sees the address
+// actually being dialled, after DNS resolution, so a host that is re-pointed
+// at an internal address after the webhook was saved is still refused.
+func checkDial(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || blocked(ip) {
+		return errInternalHost
+	}
+	return nil
+}
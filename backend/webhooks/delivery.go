@@ -0,0 +1,191 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before it fails
+	maxAttempts = 8
+	// claimLease is how long an attempt holds a delivery before another
+	// replica may take it over; longer than the client timeout
+	claimLease = time.Minute
+	// maxResponseBody is how much of a response is read before the connection
+	// is let go; none of it is kept
+	maxResponseBody = 1024
+	// retryBatch is how many due deliveries RetryDue sends at once
+	retryBatch = 100
+)
+
+var (
+	// client only connects to public addresses, without a proxy that would
+	// hide where it connects, and doesn't follow redirects, which could lead anywhere
+	client = &http.Client{
+		Timeout: 10 * time.Second,
+		Transport: &http.Transport{
+			DialContext:         (&net.Dialer{Timeout: 5 * time.Second, Control: checkDial}).DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	// retryBase is the wait after the first failed attempt; it doubles with each retry
+	retryBase = 30 * time.Second
+)
+
+// Sign returns the X-Webhook-Signature of a body sent at timestamp. Receivers
+// compute the same HMAC with the webhook's secret and compare.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff is how long to wait after the given number of failed attempts
+func backoff(attempts int) time.Duration {
+	return retryBase << (attempts - 1)
+}
+
+// Deliver makes the next attempt at a pending delivery that is due. It does
+// nothing if the delivery isn't due or another attempt has claimed it.
+func Deliver(deliveryID string) {
+	// Pushing the next attempt back claims the delivery, so concurrent callers
+	// and replicas don't send it twice
+	now := time.Now().UTC()
+	claim := config.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", deliveryID, models.DeliveryPending, now).
+		Update("next_attempt_at", now.Add(claimLease))
+	if claim.Error != nil {
+		log.Printf("Failed to claim webhook delivery %s: %v", deliveryID, claim.Error)
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+
+	var delivery models.WebhookDelivery
+	if err := config.DB.Preload("Webhook").Where("id = ?", deliveryID).First(&delivery).Error; err != nil {
+		log.Printf("Failed to load webhook delivery %s: %v", deliveryID, err)
+		return
+	}
+
+	var attempt models.WebhookAttempt
+	if delivery.Webhook.Active {
+		attempt = send(delivery.Webhook, delivery)
+	} else {
+		attempt = models.WebhookAttempt{Error: "webhook is disabled"}
+	}
+	if err := record(&delivery, attempt); err != nil {
+		log.Printf("Failed to record webhook delivery %s: %v", deliveryID, err)
+	}
+}
+
+// send posts a delivery to its webhook and reports how it went
+func send(hook models.Webhook, delivery models.WebhookDelivery) models.WebhookAttempt {
+	body := []byte(delivery.Payload)
+	start := time.Now()
+	attempt := models.WebhookAttempt{}
+
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	timestamp := start.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "trello-backend-webhooks")
+	req.Header.Set("X-Webhook-Id", hook.ID)
+	req.Header.Set("X-Webhook-Delivery", delivery.ID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", Sign(hook.Secret, timestamp, body))
+
+	resp, err := client.Do(req)
+	attempt.DurationMs = int(time.Since(start).Milliseconds())
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	code := resp.StatusCode
+	attempt.ResponseCode = &code
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	if code < 200 || code > 299 {
+		attempt.Error = resp.Status
+	}
+	return attempt
+}
+
+// record logs an attempt and moves the delivery on: done after a 2xx response,
+// failed once it runs out of attempts, otherwise scheduled for a retry
+func record(delivery *models.WebhookDelivery, attempt models.WebhookAttempt) error {
+	now := time.Now().UTC()
+	attempt.ID = uuid.NewString()
+	attempt.DeliveryID = delivery.ID
+	attempt.CreatedAt = now
+
+	attempts := delivery.Attempts + 1
+	changes := map[string]interface{}{
+		"attempts":      attempts,
+		"response_code": attempt.ResponseCode,
+		"error":         attempt.Error,
+	}
+	switch {
+	case attempt.Error == "":
+		changes["status"] = models.DeliverySucceeded
+		changes["delivered_at"] = now
+		changes["next_attempt_at"] = nil
+	case attempts >= maxAttempts || !delivery.Webhook.Active:
+		changes["status"] = models.DeliveryFailed
+		changes["next_attempt_at"] = nil
+	default:
+		changes["next_attempt_at"] = now.Add(backoff(attempts))
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Delivery").Create(&attempt).Error; err != nil {
+			return err
+		}
+		return tx.Model(delivery).Updates(changes).Error
+	})
+}
+
+// RetryDue sends every pending delivery whose next attempt is due at now
+func RetryDue(now time.Time) error {
+	var ids []string
+	if err := config.DB.Model(&models.WebhookDelivery{}).
+		Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at").Limit(retryBatch).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+
+	// One slow webhook shouldn't hold up the others
+	var wg sync.WaitGroup
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			Deliver(id)
+		}(id)
+	}
+	wg.Wait()
+	return nil
+}
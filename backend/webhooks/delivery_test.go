@@ -0,0 +1,259 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"trello-backend/config"
+	"trello-backend/models"
+	"trello-backend/testdb"
+)
+
+// allowLoopback lets deliveries reach httptest servers for the rest of the test
+func allowLoopback(t *testing.T) {
+	blocked = func(net.IP) bool { return false }
+	t.Cleanup(func() { blocked = internalIP })
+}
+
+// verifyingServer answers 204 to requests signed with secret and 401 to any other
+func verifyingServer(t *testing.T, secret string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get("X-Webhook-Timestamp") + "."))
+		mac.Write(body)
+		expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(r.Header.Get("X-Webhook-Signature")), []byte(expected)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSignatureVerifiesOnReceiver(t *testing.T) {
+	allowLoopback(t)
+	server := verifyingServer(t, "secret")
+	delivery := models.WebhookDelivery{ID: "delivery", EventType: "card.created", Payload: `{"event":"card.created"}`}
+
+	attempt := send(models.Webhook{ID: "hook", URL: server.URL, Secret: "secret"}, delivery)
+	if attempt.Error != "" || attempt.ResponseCode == nil || *attempt.ResponseCode != http.StatusNoContent {
+		t.Fatalf("signed delivery: code %v, error %q", attempt.ResponseCode, attempt.Error)
+	}
+
+	attempt = send(models.Webhook{ID: "hook", URL: server.URL, Secret: "other"}, delivery)
+	if attempt.ResponseCode == nil || *attempt.ResponseCode != http.StatusUnauthorized || attempt.Error == "" {
+		t.Fatalf("delivery signed with another secret: code %v, error %q", attempt.ResponseCode, attempt.Error)
+	}
+}
+
+func TestSendRefusesInternalAddresses(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer server.Close()
+
+	attempt := send(models.Webhook{ID: "hook", URL: server.URL}, models.WebhookDelivery{ID: "delivery"})
+	if attempt.ResponseCode != nil || !strings.Contains(attempt.Error, errInternalHost.Error()) {
+		t.Fatalf("delivery to loopback: code %v, error %q", attempt.ResponseCode, attempt.Error)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("delivery to loopback reached the server")
+	}
+}
+
+func TestSendDoesNotFollowRedirects(t *testing.T) {
+	allowLoopback(t)
+	var hits atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	attempt := send(models.Webhook{ID: "hook", URL: redirect.URL}, models.WebhookDelivery{ID: "delivery"})
+	if attempt.ResponseCode == nil || *attempt.ResponseCode != http.StatusFound || attempt.Error == "" {
+		t.Fatalf("redirected delivery: code %v, error %q", attempt.ResponseCode, attempt.Error)
+	}
+	if hits.Load() != 0 {
+		t.Fatal("redirect was followed")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://93.184.215.14/hook", true},
+		{"http://93.184.215.14:8080/hook", true},
+		{"ftp://93.184.215.14/hook", false},
+		{"/hook", false},
+		{"http://127.0.0.1/hook", false},
+		{"http://[::1]/hook", false},
+		{"http://10.1.2.3/hook", false},
+		{"http://192.168.0.10/hook", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+		{"http://100.64.0.1/hook", false},
+		{"http://0.0.0.0/hook", false},
+		{"http://[::ffff:127.0.0.1]/hook", false},
+	}
+	for _, test := range tests {
+		err := CheckURL(context.Background(), test.url)
+		if (err == nil) != test.ok {
+			t.Errorf("CheckURL(%q) = %v, want ok %v", test.url, err, test.ok)
+		}
+	}
+}
+
+func TestBackoffDoubles(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{1: retryBase, 2: 2 * retryBase, 3: 4 * retryBase, 7: 64 * retryBase} {
+		if got := backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+// openDeliveries gives the test a database holding an active webhook pointing at url
+func openDeliveries(t *testing.T, url string) models.Webhook {
+	testdb.Open(t, &models.Webhook{}, &models.WebhookDelivery{}, &models.WebhookAttempt{})
+	hook := models.Webhook{ID: "hook", BoardID: "board", CreatorID: "user", URL: url, Secret: "secret", Active: true}
+	if err := config.DB.Omit("Board", "Creator").Create(&hook).Error; err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	return hook
+}
+
+func loadDelivery(t *testing.T, id string) models.WebhookDelivery {
+	var delivery models.WebhookDelivery
+	if err := config.DB.Preload("Webhook").Where("id = ?", id).First(&delivery).Error; err != nil {
+		t.Fatalf("Failed to load delivery: %v", err)
+	}
+	return delivery
+}
+
+func TestDeliverSchedulesRetryOnErrorResponse(t *testing.T) {
+	allowLoopback(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	hook := openDeliveries(t, server.URL)
+
+	queued, err := enqueue(hook.ID, "card.created", `{}`, nil)
+	if err != nil {
+		t.Fatalf("Failed to queue delivery: %v", err)
+	}
+	before := time.Now().UTC()
+	Deliver(queued.ID)
+	after := time.Now().UTC()
+
+	delivery := loadDelivery(t, queued.ID)
+	if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+		t.Fatalf("status %q after %d attempts, want pending after 1", delivery.Status, delivery.Attempts)
+	}
+	if delivery.ResponseCode == nil || *delivery.ResponseCode != http.StatusInternalServerError {
+		t.Fatalf("response code %v, want 500", delivery.ResponseCode)
+	}
+	if next := delivery.NextAttemptAt; next == nil || next.Before(before.Add(retryBase)) || next.After(after.Add(retryBase)) {
+		t.Fatalf("next attempt at %v, want %v after the attempt", next, retryBase)
+	}
+}
+
+func TestRecordFailsDeliveryAfterMaxAttempts(t *testing.T) {
+	hook := openDeliveries(t, "https://93.184.215.14/hook")
+	queued, err := enqueue(hook.ID, "card.created", `{}`, nil)
+	if err != nil {
+		t.Fatalf("Failed to queue delivery: %v", err)
+	}
+
+	code := http.StatusBadGateway
+	for n := 1; n <= maxAttempts; n++ {
+		delivery := loadDelivery(t, queued.ID)
+		before := time.Now().UTC()
+		if err := record(&delivery, models.WebhookAttempt{ResponseCode: &code, Error: "502 Bad Gateway"}); err != nil {
+			t.Fatalf("Failed to record attempt %d: %v", n, err)
+		}
+
+		delivery = loadDelivery(t, queued.ID)
+		if delivery.Attempts != n {
+			t.Fatalf("attempts = %d, want %d", delivery.Attempts, n)
+		}
+		if n < maxAttempts {
+			if delivery.Status != models.DeliveryPending || delivery.NextAttemptAt == nil ||
+				delivery.NextAttemptAt.Before(before.Add(backoff(n))) {
+				t.Fatalf("attempt %d: status %q, next attempt at %v", n, delivery.Status, delivery.NextAttemptAt)
+			}
+			continue
+		}
+		if delivery.Status != models.DeliveryFailed || delivery.NextAttemptAt != nil {
+			t.Fatalf("last attempt: status %q, next attempt at %v, want failed with none", delivery.Status, delivery.NextAttemptAt)
+		}
+	}
+
+	var logged int64
+	config.DB.Model(&models.WebhookAttempt{}).Where("delivery_id = ?", queued.ID).Count(&logged)
+	if logged != maxAttempts {
+		t.Fatalf("%d attempts logged, want %d", logged, maxAttempts)
+	}
+}
+
+func TestReplaySendsPayloadAsNewDelivery(t *testing.T) {
+	allowLoopback(t)
+	type request struct{ delivery, body string }
+	received := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{r.Header.Get("X-Webhook-Delivery"), string(body)}
+	}))
+	defer server.Close()
+	hook := openDeliveries(t, server.URL)
+
+	original, err := enqueue(hook.ID, "card.moved", `{"event":"card.moved"}`, nil)
+	if err != nil {
+		t.Fatalf("Failed to queue delivery: %v", err)
+	}
+	config.DB.Model(&original).Updates(map[string]interface{}{"status": models.DeliveryFailed, "next_attempt_at": nil})
+
+	replay, err := Replay(original)
+	if err != nil {
+		t.Fatalf("Failed to replay delivery: %v", err)
+	}
+	if replay.ID == original.ID || replay.ReplayOf == nil || *replay.ReplayOf != original.ID {
+		t.Fatalf("replay %s of %v, want a new delivery replaying %s", replay.ID, replay.ReplayOf, original.ID)
+	}
+
+	select {
+	case got := <-received:
+		if got.delivery != replay.ID || got.body != original.Payload {
+			t.Fatalf("received delivery %s with %s, want %s with %s", got.delivery, got.body, replay.ID, original.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("replay was never sent")
+	}
+
+	// Wait for the result to be recorded so the database outlives the send
+	deadline := time.Now().Add(5 * time.Second)
+	for loadDelivery(t, replay.ID).Status != models.DeliverySucceeded {
+		if time.Now().After(deadline) {
+			t.Fatal("replay was never marked delivered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := loadDelivery(t, original.ID).Status; status != models.DeliveryFailed {
+		t.Fatalf("original status changed to %q", status)
+	}
+}
@@ -0,0 +1,93 @@
+// Package webhooks posts board events to the URLs registered on a board. Each
+// event becomes a delivery that is signed, sent in the background and retried
+// with exponential backoff, with every attempt kept in a log.
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"slices"
+	"time"
+	"trello-backend/config"
+	"trello-backend/events"
+	"trello-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// payload is the JSON body of a delivery
+type payload struct {
+	Event   string          `json:"event"`
+	BoardID string          `json:"boardId"`
+	ActorID string          `json:"actorId"`
+	Data    json.RawMessage `json:"data"`
+	At      time.Time       `json:"at"`
+}
+
+// FromEvent queues a delivery of the event to every active webhook of its board
+// whose filter lets it through, and starts sending them. It is registered with events.Handle.
+func FromEvent(event events.Event) {
+	var hooks []models.Webhook
+	if err := config.DB.Where("board_id = ? AND active = ?", event.BoardID, true).Find(&hooks).Error; err != nil {
+		log.Printf("Failed to find webhooks for %s event: %v", event.Type, err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(payload{event.Type, event.BoardID, event.ActorID, event.Data, event.At})
+	if err != nil {
+		log.Printf("Failed to encode %s event for webhooks: %v", event.Type, err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, event.Type) {
+			continue
+		}
+		delivery, err := enqueue(hook.ID, event.Type, string(body), nil)
+		if err != nil {
+			log.Printf("Failed to queue webhook delivery for %s: %v", hook.ID, err)
+			continue
+		}
+		go Deliver(delivery.ID)
+	}
+}
+
+// Replay queues the payload of an earlier delivery again as a new delivery and starts sending it
+func Replay(original models.WebhookDelivery) (models.WebhookDelivery, error) {
+	delivery, err := enqueue(original.WebhookID, original.EventType, original.Payload, &original.ID)
+	if err != nil {
+		return delivery, err
+	}
+	go Deliver(delivery.ID)
+	return delivery, nil
+}
+
+func enqueue(webhookID, eventType, body string, replayOf *string) (models.WebhookDelivery, error) {
+	now := time.Now().UTC()
+	delivery := models.WebhookDelivery{
+		ID:            uuid.NewString(),
+		WebhookID:     webhookID,
+		EventType:     eventType,
+		Payload:       body,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &now,
+		ReplayOf:      replayOf,
+		CreatedAt:     now,
+	}
+	err := config.DB.Omit("Webhook").Create(&delivery).Error
+	return delivery, err
+}
+
+// NewSecret returns a random secret for signing a webhook's deliveries
+func NewSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -29,8 +29,8 @@ export function DeleteBoardModal({
         <DialogHeader>
           <DialogTitle>Delete Board</DialogTitle>
           <DialogDescription>
-            Are you sure you want to delete this board? This action cannot be
-            undone.
+            Are you sure you want to delete this board? It will be moved to the
+            trash, where it can be restored for 30 days.
           </DialogDescription>
         </DialogHeader>
         <DialogFooter>
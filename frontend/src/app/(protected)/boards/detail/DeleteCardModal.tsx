@@ -30,7 +30,7 @@ const DeleteCardModal: React.FC<DeleteCardModalProps> = ({
         <DialogHeader>
           <DialogTitle>Delete Card</DialogTitle>
             <DialogDescription>
-            Are you sure you want to delete &ldquo;{cardTitle}&rdquo;? This action cannot be undone.
+            Are you sure you want to delete &ldquo;{cardTitle}&rdquo;? It will be moved to the trash, where it can be restored for 30 days.
             </DialogDescription>
         </DialogHeader>
         <DialogFooter>
@@ -14,7 +14,7 @@ interface Card {
   id: string;
   title: string;
   description: string;
-  deadline?: string;
+  dueDate?: string | null;
 }
 
 interface List {
@@ -149,8 +149,8 @@ const KanbanBoard: React.FC<KanbanBoardProps> = ({
                           {card.description}
                         </p>
 
-                        {card.deadline && (
-                          <DeadlineDisplay deadline={card.deadline} />
+                        {card.dueDate && (
+                          <DeadlineDisplay deadline={card.dueDate} />
                         )}
                       </div>
                     )}
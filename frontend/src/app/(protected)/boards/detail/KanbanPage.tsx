@@ -52,7 +52,7 @@ const KanbanPage: React.FC = () => {
         description,
         position:
           board.lists.find((l) => l.id === selectedListId)?.cards.length || 0,
-        deadline: date,
+        dueDate: date,
       });
 
       await mutate();
@@ -97,12 +97,13 @@ export const createCard = async (
     title: string;
     description: string;
     position: number;
-    deadline?: string;
+    dueDate?: string;
   }
 ) => {
-  // change 2024-12-11 format to 2024-12-11T00:00:00Z
-  const date = new Date(card.deadline!).toISOString().replace(/T.*$/, "Z");
-  card.deadline = date;
+  // send the picked date as a full UTC timestamp
+  if (card.dueDate) {
+    card.dueDate = new Date(card.dueDate).toISOString();
+  }
 
   const res = await fetch(
     `${BASE_URL}/board/${boardId}/lists/${listId}/cards`,